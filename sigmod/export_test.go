@@ -0,0 +1,17 @@
+package sigmod
+
+import "os"
+
+// SetParentPid replaces parent pid lookup until returned func is called.
+func SetParentPid(fn func() int) (restore func()) {
+	orig := getppid
+	getppid = fn
+	return func() { getppid = orig }
+}
+
+// SetStdin replaces stdin until returned func is called.
+func SetStdin(f *os.File) (restore func()) {
+	orig := stdin
+	stdin = f
+	return func() { stdin = orig }
+}
@@ -0,0 +1,173 @@
+package sigmod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const NotifierID = "sdnotify"
+
+const (
+	ErrInvalidWatchdogUsec = errStr("invalid WATCHDOG_USEC")
+	ErrNotifyFailed        = errStr("failed to notify systemd")
+	ErrInvalidTimeout      = errStr("liveness timeout must be positive")
+)
+
+// Notifier implements the systemd notify protocol for Type=notify units.
+// It sends READY=1 from Run, which srvc calls only after every module
+// has been initialized, and STOPPING=1 from Stop. Place it last so that
+// its Stop is the first one called on shutdown.
+//
+// When WATCHDOG_USEC is set, WATCHDOG=1 is sent every half of it as long
+// as all liveness checks pass.
+//
+// Without NOTIFY_SOCKET the Notifier does nothing.
+type Notifier struct {
+	conn     *net.UnixConn
+	watchdog time.Duration
+	timeout  time.Duration
+	checks   []func(context.Context) error
+	done     chan struct{}
+	wg       sync.WaitGroup
+	opts     []NotifierOpt
+}
+
+// NewNotifier creates systemd notifier configured from NOTIFY_SOCKET,
+// WATCHDOG_USEC and WATCHDOG_PID environment variables.
+func NewNotifier(opts ...NotifierOpt) *Notifier {
+	return &Notifier{opts: opts}
+}
+
+func (n *Notifier) Init() error {
+	n.done = make(chan struct{})
+	n.timeout = time.Second
+	for _, opt := range n.opts {
+		if err := opt(n); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotifyFailed, err)
+	}
+	n.conn = conn
+
+	watchdog, err := watchdogInterval()
+	if err != nil {
+		return errors.Join(err, conn.Close())
+	}
+	n.watchdog = watchdog
+	return nil
+}
+
+func (n *Notifier) Run() error {
+	if err := n.notify("READY=1"); err != nil {
+		return err
+	}
+	if n.watchdog > 0 {
+		n.wg.Go(n.ping)
+	}
+	<-n.done
+	return nil
+}
+
+func (n *Notifier) Stop() error {
+	close(n.done)
+	n.wg.Wait()
+	if n.conn == nil {
+		return nil
+	}
+	return errors.Join(n.notify("STOPPING=1"), n.conn.Close())
+}
+
+func (n *Notifier) ID() string { return NotifierID }
+
+// ping sends WATCHDOG=1 until Stop. Failed liveness checks skip the ping
+// so that systemd can restart the unit once the watchdog timeout passes.
+func (n *Notifier) ping() {
+	t := time.NewTicker(n.watchdog)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if n.alive() {
+				_ = n.notify("WATCHDOG=1")
+			}
+		case <-n.done:
+			return
+		}
+	}
+}
+
+func (n *Notifier) alive() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+	for _, check := range n.checks {
+		if check(ctx) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Notifier) notify(state string) error {
+	if n.conn == nil {
+		return nil
+	}
+	if _, err := n.conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotifyFailed, err)
+	}
+	return nil
+}
+
+// watchdogInterval returns half of WATCHDOG_USEC, or zero when the
+// watchdog is disabled or meant for another process.
+func watchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	us, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || us <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidWatchdogUsec, usec)
+	}
+	return time.Duration(us) * time.Microsecond / 2, nil
+}
+
+type NotifierOpt func(*Notifier) error
+
+// WithLivenessCheck adds a check that must pass before each watchdog ping.
+// Checks run sequentially and share the timeout set by WithLivenessTimeout.
+func WithLivenessCheck(fn func(context.Context) error) NotifierOpt {
+	return func(n *Notifier) error {
+		n.checks = append(n.checks, fn)
+		return nil
+	}
+}
+
+// WithLivenessTimeout bounds the time all liveness checks may take per ping.
+// Default is one second.
+func WithLivenessTimeout(d time.Duration) NotifierOpt {
+	return func(n *Notifier) error {
+		if d <= 0 {
+			return ErrInvalidTimeout
+		}
+		n.timeout = d
+		return nil
+	}
+}
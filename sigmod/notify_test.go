@@ -0,0 +1,129 @@
+//go:build !windows
+
+package sigmod_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sigmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readState(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestNotifierReadyStopping(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "")
+
+	n := sigmod.NewNotifier()
+	require.NoError(t, n.Init())
+	require.Equal(t, "sdnotify", n.ID())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(n.Run)
+
+	require.Equal(t, "READY=1", readState(t, conn))
+	require.NoError(t, n.Stop())
+	require.NoError(t, wg.Wait())
+	require.Equal(t, "STOPPING=1", readState(t, conn))
+}
+
+func TestNotifierWatchdog(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+
+	healthy := atomic.Bool{}
+	healthy.Store(true)
+	n := sigmod.NewNotifier(
+		sigmod.WithLivenessCheck(func(ctx context.Context) error {
+			if healthy.Load() {
+				return nil
+			}
+			return errors.New("unhealthy")
+		}),
+	)
+	require.NoError(t, n.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(n.Run)
+
+	require.Equal(t, "READY=1", readState(t, conn))
+	require.Equal(t, "WATCHDOG=1", readState(t, conn))
+
+	healthy.Store(false)
+	// Drain a ping that may have been sent before the check started failing.
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(15*time.Millisecond)))
+	_, _ = conn.Read(make([]byte, 256))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err := conn.Read(make([]byte, 256))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded, "watchdog ping sent while liveness check fails")
+
+	require.NoError(t, n.Stop())
+	require.NoError(t, wg.Wait())
+	require.Equal(t, "STOPPING=1", readState(t, conn))
+}
+
+func TestNotifierWatchdogOtherPid(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "1")
+
+	n := sigmod.NewNotifier()
+	require.NoError(t, n.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(n.Run)
+
+	require.Equal(t, "READY=1", readState(t, conn))
+	require.NoError(t, n.Stop())
+	require.NoError(t, wg.Wait())
+	require.Equal(t, "STOPPING=1", readState(t, conn))
+}
+
+func TestNotifierInvalidWatchdog(t *testing.T) {
+	listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "soon")
+
+	n := sigmod.NewNotifier()
+	require.ErrorIs(t, n.Init(), sigmod.ErrInvalidWatchdogUsec)
+}
+
+func TestNotifierInvalidTimeout(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	n := sigmod.NewNotifier(sigmod.WithLivenessTimeout(0))
+	require.ErrorIs(t, n.Init(), sigmod.ErrInvalidTimeout)
+}
+
+func TestNotifierNoSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	n := sigmod.NewNotifier()
+	require.NoError(t, n.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(n.Run)
+	require.NoError(t, n.Stop())
+	require.NoError(t, wg.Wait())
+}
@@ -0,0 +1,5 @@
+//go:build !windows
+
+package sigmod
+
+const parentWatchSupported = true
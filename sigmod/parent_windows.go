@@ -0,0 +1,4 @@
+package sigmod
+
+// Windows doesn't re-parent orphaned processes so parent pid never changes.
+const parentWatchSupported = false
@@ -2,43 +2,207 @@
 package sigmod
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"sync"
+	"time"
 )
 
 const ID = "sigmod"
 
+const (
+	ErrInvalidPollInterval    = errStr("poll interval must be positive")
+	ErrParentWatchUnsupported = errStr("parent death watch not supported on this platform")
+)
+
+type errStr string
+
+func (e errStr) Error() string { return string(e) }
+
+// Replaced in tests.
+var (
+	getppid = os.Getppid
+	stdin   = os.Stdin
+)
+
+// Reason describes what made Run return.
+type Reason string
+
+const (
+	// ReasonNone means Run has not returned or returned because of Stop.
+	ReasonNone Reason = ""
+	// ReasonSignal means one of the listened OS signals was received.
+	ReasonSignal Reason = "signal"
+	// ReasonParentDeath means the parent process exited.
+	ReasonParentDeath Reason = "parent death"
+	// ReasonStdinClosed means stdin reached EOF.
+	ReasonStdinClosed Reason = "stdin closed"
+)
+
 type Listener struct {
 	ch   chan os.Signal
 	sigs []os.Signal
+	opts []Opt
+
+	parentPoll time.Duration
+	watchStdin bool
+	trigger    chan Reason
+	done       chan struct{}
+	wg         sync.WaitGroup
+
+	mu     sync.Mutex
+	reason Reason
+	sig    os.Signal
 }
 
 // New creates signal listener for given signals.
 // If no signal is provided, os.Interrupt will be used.
 func New(signals ...os.Signal) *Listener {
-	if len(signals) == 0 {
-		signals = defaultSignals
-	}
-	return &Listener{
-		sigs: signals,
-	}
+	return NewWithOpts(WithSignals(signals...))
+}
+
+// NewWithOpts creates signal listener with given options.
+// If WithSignals is not provided, default signals will be used.
+func NewWithOpts(opts ...Opt) *Listener {
+	return &Listener{opts: opts}
 }
 
 func (l *Listener) Init() error {
+	l.sigs = nil
+	for _, opt := range l.opts {
+		if err := opt(l); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	if len(l.sigs) == 0 {
+		l.sigs = defaultSignals
+	}
+
+	l.trigger = make(chan Reason, 2)
+	l.done = make(chan struct{})
+	if l.parentPoll > 0 {
+		getppid, ppid := getppid, getppid()
+		l.wg.Go(func() { l.watchParent(getppid, ppid, l.parentPoll) })
+	}
+	if l.watchStdin {
+		go l.watchEOF(stdin)
+	}
+
 	l.ch = make(chan os.Signal, 1)
 	signal.Notify(l.ch, l.sigs...)
 	return nil
 }
 
 func (l *Listener) Run() error {
-	<-l.ch
+	select {
+	case sig, ok := <-l.ch:
+		if ok {
+			l.setReason(ReasonSignal, sig)
+		}
+	case r := <-l.trigger:
+		l.setReason(r, nil)
+	}
 	return nil
 }
 
 func (l *Listener) Stop() error {
-	defer close(l.ch)
 	signal.Stop(l.ch)
+	close(l.done)
+	l.wg.Wait()
+	close(l.ch)
 	return nil
 }
 
 func (l *Listener) ID() string { return ID }
+
+// Reason returns what made Run return.
+func (l *Listener) Reason() Reason {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reason
+}
+
+// Signal returns the received signal when Reason is ReasonSignal, nil otherwise.
+func (l *Listener) Signal() os.Signal {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sig
+}
+
+func (l *Listener) setReason(r Reason, sig os.Signal) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reason, l.sig = r, sig
+}
+
+func (l *Listener) fire(r Reason) {
+	select {
+	case l.trigger <- r:
+	default:
+	}
+}
+
+func (l *Listener) watchParent(getppid func() int, ppid int, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if getppid() != ppid {
+				l.fire(ReasonParentDeath)
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// watchEOF drains r until EOF or read error. The goroutine can't be
+// interrupted while blocked in Read, so it may outlive Stop.
+func (l *Listener) watchEOF(r io.Reader) {
+	_, _ = io.Copy(io.Discard, r)
+	select {
+	case <-l.done:
+	default:
+		l.fire(ReasonStdinClosed)
+	}
+}
+
+type Opt func(*Listener) error
+
+// WithSignals sets the OS signals to listen for.
+func WithSignals(signals ...os.Signal) Opt {
+	return func(l *Listener) error {
+		l.sigs = append(l.sigs, signals...)
+		return nil
+	}
+}
+
+// WithParentDeath returns from Run with ReasonParentDeath when the parent
+// process exits. The parent pid is polled on given interval and a change
+// means the process was re-parented. Not supported on Windows.
+func WithParentDeath(interval time.Duration) Opt {
+	return func(l *Listener) error {
+		if !parentWatchSupported {
+			return ErrParentWatchUnsupported
+		}
+		if interval <= 0 {
+			return ErrInvalidPollInterval
+		}
+		l.parentPoll = interval
+		return nil
+	}
+}
+
+// WithStdinClose returns from Run with ReasonStdinClosed when stdin reaches EOF.
+// Anything written to stdin is discarded. Only use this when the parent keeps
+// stdin open as a pipe, since /dev/null triggers shutdown immediately.
+func WithStdinClose() Opt {
+	return func(l *Listener) error {
+		l.watchStdin = true
+		return nil
+	}
+}
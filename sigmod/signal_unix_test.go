@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/go-srvc/mods/sigmod"
+	"github.com/heppu/errgroup"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,7 +22,11 @@ func TestListenerDefaultSignal(t *testing.T) {
 		t.Run(sig.String(), func(t *testing.T) {
 			l := sigmod.New()
 			require.NoError(t, l.Init())
+			wg := &errgroup.ErrGroup{}
+			wg.Go(l.Run)
 			require.NoError(t, syscall.Kill(syscall.Getpid(), sig))
+			require.NoError(t, wg.Wait())
+			require.Equal(t, sig, l.Signal())
 			require.NoError(t, l.Stop())
 			require.Equal(t, "sigmod", l.ID())
 		})
@@ -33,5 +38,7 @@ func TestListenerOpts(t *testing.T) {
 	require.NoError(t, l.Init())
 	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
 	require.NoError(t, l.Run())
+	require.Equal(t, sigmod.ReasonSignal, l.Reason())
+	require.Equal(t, os.Interrupt, l.Signal())
 	require.NoError(t, l.Stop())
 }
@@ -0,0 +1,68 @@
+//go:build !windows
+
+package sigmod_test
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sigmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParentDeath(t *testing.T) {
+	ppid := atomic.Int64{}
+	ppid.Store(42)
+	defer sigmod.SetParentPid(func() int { return int(ppid.Load()) })()
+
+	l := sigmod.NewWithOpts(
+		sigmod.WithSignals(os.Interrupt),
+		sigmod.WithParentDeath(time.Millisecond),
+	)
+	require.NoError(t, l.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(l.Run)
+
+	ppid.Store(1)
+	require.NoError(t, wg.Wait())
+	require.Equal(t, sigmod.ReasonParentDeath, l.Reason())
+	require.Nil(t, l.Signal())
+	require.NoError(t, l.Stop())
+}
+
+func TestStdinClose(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer sigmod.SetStdin(r)()
+
+	l := sigmod.NewWithOpts(sigmod.WithStdinClose())
+	require.NoError(t, l.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(l.Run)
+
+	_, err = w.WriteString("ignored\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, wg.Wait())
+	require.Equal(t, sigmod.ReasonStdinClosed, l.Reason())
+	require.NoError(t, l.Stop())
+}
+
+func TestStopReason(t *testing.T) {
+	l := sigmod.NewWithOpts(sigmod.WithParentDeath(time.Hour))
+	require.NoError(t, l.Init())
+
+	wg := &errgroup.ErrGroup{}
+	wg.Go(l.Run)
+	require.NoError(t, l.Stop())
+	require.NoError(t, wg.Wait())
+	require.Equal(t, sigmod.ReasonNone, l.Reason())
+}
+
+func TestInvalidPollInterval(t *testing.T) {
+	l := sigmod.NewWithOpts(sigmod.WithParentDeath(0))
+	require.ErrorIs(t, l.Init(), sigmod.ErrInvalidPollInterval)
+}
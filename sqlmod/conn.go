@@ -0,0 +1,362 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// connector wraps the connector of the driver so that every query and
+// transaction goes through the tracker and query statistics of the module.
+type connector struct {
+	driver.Connector
+	db *DB
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if source, ok := c.Connector.(*funcConnector); ok {
+		dc, gen, err := source.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &conn{Conn: dc, db: c.db, source: source, gen: gen}, nil
+	}
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, db: c.db}, nil
+}
+
+// Close implements io.Closer called by sql.DB.Close.
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dsnConnector is used for drivers not implementing driver.DriverContext.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// conn forwards to the driver connection. Optional interfaces the driver
+// doesn't implement fall back to what database/sql would do without them.
+type conn struct {
+	driver.Conn
+	db     *DB
+	inTx   bool
+	source *funcConnector
+	gen    uint64
+}
+
+// Raw returns the connection of the driver, see UnwrapConn.
+func (c *conn) Raw() driver.Conn { return c.Conn }
+
+// UnwrapConn returns the connection of the driver passed to sql.Conn.Raw by
+// databases opened with WithDSN, WithOtel or WithConnector, which wrap it to
+// track queries when WithShutdownTimeout, WithRecycleOnChange, WithSlowQueryLog
+// or WithQueryMetrics is set. Wrappers with a Raw method returning the inner connection,
+// like the one of otelsql, are removed as well. Other values are returned
+// as is, for example:
+//
+//	err := conn.Raw(func(dc any) error {
+//		c := sqlmod.UnwrapConn(dc).(*stdlib.Conn)
+//		...
+//	})
+func UnwrapConn(driverConn any) any {
+	for {
+		r, ok := driverConn.(interface{ Raw() driver.Conn })
+		if !ok {
+			return driverConn
+		}
+		driverConn = r.Raw()
+	}
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, done, err := c.db.track.start(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var tx driver.Tx
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqlmod: driver does not support transaction options")
+	} else {
+		//nolint:staticcheck // Fallback for drivers without ConnBeginTx.
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		done()
+		return nil, err
+	}
+	c.inTx = true
+	return &txWrap{Tx: tx, conn: c, done: done}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmtWrap{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, done, err := c.db.track.start(ctx, c.inTx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.db.stats.query(ctx, query, len(args), time.Since(start), err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, done, err := c.db.track.start(ctx, c.inTx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	c.db.stats.query(ctx, query, len(args), time.Since(start), err)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &rowsWrap{Rows: rows, done: done}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.source != nil && c.source.stale(c.gen) {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if c.source != nil && c.source.stale(c.gen) {
+		return false
+	}
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type txWrap struct {
+	driver.Tx
+	conn *conn
+	done func()
+}
+
+func (t *txWrap) Commit() error {
+	defer t.end()
+	return t.Tx.Commit()
+}
+
+func (t *txWrap) Rollback() error {
+	defer t.end()
+	return t.Tx.Rollback()
+}
+
+func (t *txWrap) end() {
+	t.conn.inTx = false
+	t.done()
+}
+
+type stmtWrap struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+func (s *stmtWrap) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done, err := s.conn.db.track.start(ctx, s.conn.inTx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	start := time.Now()
+	res, err := s.exec(ctx, args)
+	s.conn.db.stats.query(ctx, s.query, len(args), time.Since(start), err)
+	return res, err
+}
+
+func (s *stmtWrap) exec(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	//nolint:staticcheck // Fallback for drivers without StmtExecContext.
+	return s.Stmt.Exec(values)
+}
+
+func (s *stmtWrap) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done, err := s.conn.db.track.start(ctx, s.conn.inTx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := s.queryRows(ctx, args)
+	s.conn.db.stats.query(ctx, s.query, len(args), time.Since(start), err)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &rowsWrap{Rows: rows, done: done}, nil
+}
+
+func (s *stmtWrap) queryRows(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	//nolint:staticcheck // Fallback for drivers without StmtQueryContext.
+	return s.Stmt.Query(values)
+}
+
+func (s *stmtWrap) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqlmod: driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// rowsWrap keeps the query tracked until the rows are closed.
+type rowsWrap struct {
+	driver.Rows
+	done func()
+	once sync.Once
+}
+
+func (r *rowsWrap) Close() error {
+	defer r.once.Do(r.done)
+	return r.Rows.Close()
+}
+
+func (r *rowsWrap) HasNextResultSet() bool {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rowsWrap) NextResultSet() error {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *rowsWrap) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *rowsWrap) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rowsWrap) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rowsWrap) ColumnTypeNullable(index int) (bool, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rowsWrap) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
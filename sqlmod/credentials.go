@@ -0,0 +1,148 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ErrInvalidRecycle = errStr("recycle interval must be positive")
+	ErrDSNFuncNotSet  = errStr("recycling requires WithDSNFunc")
+)
+
+// DSNFunc returns the DSN used for opening a new connection.
+type DSNFunc func(ctx context.Context) (string, error)
+
+// DSNFromFile returns DSNFunc reading the DSN from a file, for example one
+// kept up to date by a sidecar rotating credentials.
+func DSNFromFile(path string) DSNFunc {
+	return func(context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
+
+// funcConnector opens connections with the DSN returned by fn. The
+// generation is increased whenever the DSN changes, so that connections
+// opened with old credentials can be recycled.
+type funcConnector struct {
+	drv driver.Driver
+	fn  DSNFunc
+
+	mu        sync.Mutex
+	dsn       string
+	connector driver.Connector
+	gen       atomic.Uint64
+	recycle   bool
+}
+
+func (c *funcConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, _, err := c.connect(ctx)
+	return dc, err
+}
+
+func (c *funcConnector) Driver() driver.Driver { return c.drv }
+
+// Close closes the connector of the current DSN, see connector.Close.
+func (c *funcConnector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if closer, ok := c.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// connect opens a connection and returns the generation of its DSN.
+func (c *funcConnector) connect(ctx context.Context) (driver.Conn, uint64, error) {
+	dsn, err := c.fn(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get dsn: %w", err)
+	}
+	connector, gen, err := c.update(dsn)
+	if err != nil {
+		return nil, 0, err
+	}
+	dc, err := connector.Connect(ctx)
+	return dc, gen, err
+}
+
+// refresh calls fn to find out whether the DSN has changed.
+func (c *funcConnector) refresh(ctx context.Context) error {
+	dsn, err := c.fn(ctx)
+	if err != nil {
+		return err
+	}
+	_, _, err = c.update(dsn)
+	return err
+}
+
+func (c *funcConnector) update(dsn string) (driver.Connector, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.connector != nil && dsn == c.dsn {
+		return c.connector, c.gen.Load(), nil
+	}
+	var connector driver.Connector = dsnConnector{dsn: dsn, drv: c.drv}
+	if dc, ok := c.drv.(driver.DriverContext); ok {
+		var err error
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, 0, err
+		}
+	}
+	if c.connector != nil {
+		c.gen.Add(1)
+	}
+	c.dsn, c.connector = dsn, connector
+	return connector, c.gen.Load(), nil
+}
+
+// stale reports whether a connection of given generation must be recycled.
+func (c *funcConnector) stale(gen uint64) bool {
+	return c.recycle && gen != c.gen.Load()
+}
+
+// WithDSNFunc opens a *sql.DB that calls fn for the DSN whenever a new
+// connection is opened, so that rotated credentials are picked up. fn is
+// also called once by the option to look up the driver.
+func WithDSNFunc(driverName string, fn DSNFunc) Opt {
+	return func(d *DB) error {
+		dsn, err := fn(context.Background())
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrFailedOpenDB, err)
+		}
+		c, err := openConnector(driverName, dsn)
+		if err != nil {
+			return err
+		}
+		d.source = &funcConnector{drv: c.Driver(), fn: fn, dsn: dsn, connector: c}
+		d.setConnector(d.source, driverName, false, nil)
+		return nil
+	}
+}
+
+// WithRecycleOnChange makes connections opened with an old DSN get closed
+// instead of reused once the DSN returned by the function set with
+// WithDSNFunc changes, for databases that drop connections when their
+// credentials expire. Besides new connections, the function is called every
+// interval while the module runs. Init fails with ErrDSNFuncNotSet without
+// WithDSNFunc.
+func WithRecycleOnChange(interval time.Duration) Opt {
+	return func(d *DB) error {
+		if interval <= 0 {
+			return ErrInvalidRecycle
+		}
+		d.recycleInterval = interval
+		return nil
+	}
+}
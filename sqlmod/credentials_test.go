@@ -0,0 +1,79 @@
+package sqlmod_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDSNFunc(t *testing.T) {
+	dir := t.TempDir()
+	openNamed(t, filepath.Join(dir, "old.db"), "old")
+	openNamed(t, filepath.Join(dir, "new.db"), "new")
+	dsn := filepath.Join(dir, "old.db")
+	calls := 0
+	dbx := sqlmod.New(
+		sqlmod.WithDSNFunc("sqlite3", func(context.Context) (string, error) {
+			calls++
+			return dsn, nil
+		}),
+		sqlmod.WithMaxOpenConns(2),
+	)
+	require.NoError(t, dbx.Init())
+	require.Equal(t, "old", name(t, dbx.DB()))
+	require.Equal(t, 2, calls, "called by the option and for the connection")
+
+	// Without recycling the idle connection keeps being used.
+	dsn = filepath.Join(dir, "new.db")
+	require.Equal(t, "old", name(t, dbx.DB()))
+	tx, err := dbx.DB().Begin()
+	require.NoError(t, err)
+	require.Equal(t, "new", name(t, dbx.DB()), "new connection while the first one is busy")
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, dbx.Stop())
+}
+
+func TestDSNFunc_Recycle(t *testing.T) {
+	dir := t.TempDir()
+	openNamed(t, filepath.Join(dir, "old.db"), "old")
+	openNamed(t, filepath.Join(dir, "new.db"), "new")
+	file := filepath.Join(dir, "dsn")
+	require.NoError(t, os.WriteFile(file, []byte(filepath.Join(dir, "old.db")+"\n"), 0o600))
+	dbx := sqlmod.New(
+		sqlmod.WithDSNFunc("sqlite3", sqlmod.DSNFromFile(file)),
+		sqlmod.WithRecycleOnChange(time.Millisecond),
+	)
+	require.NoError(t, dbx.Init())
+	require.Equal(t, "old", name(t, dbx.DB()))
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+
+	require.NoError(t, os.WriteFile(file, []byte(filepath.Join(dir, "new.db")), 0o600))
+	require.Eventually(t, func() bool { return name(t, dbx.DB()) == "new" }, time.Second, time.Millisecond)
+	require.Equal(t, 1, dbx.DB().Stats().OpenConnections, "old connection closed")
+
+	require.NoError(t, dbx.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestDSNFunc_Errors(t *testing.T) {
+	errDSN := errors.New("no credentials")
+	dbx := sqlmod.New(sqlmod.WithDSNFunc("sqlite3", func(context.Context) (string, error) { return "", errDSN }))
+	require.ErrorIs(t, dbx.Init(), errDSN)
+
+	dbx = sqlmod.New(sqlmod.WithDSNFunc("sqlite3", sqlmod.DSNFromFile(filepath.Join(t.TempDir(), "missing"))))
+	require.ErrorIs(t, dbx.Init(), os.ErrNotExist)
+
+	dbx = sqlmod.New(sqlmod.WithDB(nil), sqlmod.WithRecycleOnChange(0))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidRecycle)
+
+	dbx = sqlmod.New(sqlmod.WithDSN("sqlite3", ":memory:"), sqlmod.WithRecycleOnChange(time.Minute))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrDSNFuncNotSet)
+}
@@ -2,8 +2,16 @@
 package sqlmod
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/XSAM/otelsql"
 )
@@ -13,16 +21,70 @@ const ID = "sqlmod"
 const (
 	ErrDBNotSet     = errStr("db not set")
 	ErrFailedOpenDB = errStr("failed to open db")
+	ErrPingFailed   = errStr("failed to ping db")
+	ErrInvalidPing  = errStr("ping timeout and backoff must be positive")
+	ErrInvalidID    = errStr("id must not be empty")
 )
 
+// dollarDrivers are the driver names using $1 style placeholders, as in
+// sqlx.BindType.
+var dollarDrivers = []string{"postgres", "pgx", "pq-timeouts", "cloudsqlpostgres", "ql", "nrpostgres", "cockroach"}
+
 type errStr string
 
 func (e errStr) Error() string { return string(e) }
 
 type DB struct {
+	id   string
 	db   *sql.DB
 	done chan struct{}
 	opts []Opt
+
+	connector  driver.Connector
+	driverName string
+	otel       bool
+	otelOpts   []otelsql.Option
+
+	pool        []func(*sql.DB)
+	pingTimeout time.Duration
+	pingMin     time.Duration
+	pingMax     time.Duration
+
+	txAttempts int
+	txMin      time.Duration
+	txMax      time.Duration
+	retryable  RetryClassifier
+
+	primary        *DB
+	replicas       []*replica
+	nextReplica    atomic.Uint64
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+
+	migrations  fs.FS
+	migrateOpts []MigrateOpt
+	migrator    *Migrator
+
+	track           *tracker
+	shutdownTimeout time.Duration
+
+	source          *funcConnector
+	recycleInterval time.Duration
+
+	stats        *stats
+	slowQuery    time.Duration
+	queryMetrics bool
+	poolInterval time.Duration
+
+	health        *health
+	checkTimeout  time.Duration
+	checkQuery    string
+	checkCache    time.Duration
+	checkInterval time.Duration
+
+	queries       context.Context
+	cancelQueries context.CancelFunc
+	queryTimeout  time.Duration
 }
 
 // New creates new sql module with given options.
@@ -33,48 +95,272 @@ func New(opts ...Opt) *DB {
 
 func (d *DB) Init() error {
 	d.done = make(chan struct{})
+	d.id = ID
+	d.db, d.connector, d.driverName, d.otel, d.otelOpts = nil, nil, "", false, nil
+	d.pool = nil
+	d.migrations, d.migrateOpts, d.migrator = nil, nil, nil
+	d.pingMin = 100 * time.Millisecond
+	d.pingMax = 5 * time.Second
+	d.txAttempts, d.txMin, d.txMax = 3, 10*time.Millisecond, time.Second
+	d.retryable = RetryDefault
+	d.replicas = nil
+	d.healthInterval, d.healthTimeout = 5*time.Second, time.Second
+	d.track, d.shutdownTimeout = newTracker(), 0
+	d.source, d.recycleInterval = nil, 0
+	d.slowQuery, d.queryMetrics, d.poolInterval = 0, false, 0
+	d.checkTimeout, d.checkQuery, d.checkCache, d.checkInterval = time.Second, "", 0, 0
+	d.queries, d.cancelQueries = context.WithCancel(context.Background())
+	d.queryTimeout = 30 * time.Second
 	for _, opt := range d.opts {
 		if err := opt(d); err != nil {
 			return fmt.Errorf("failed to apply option: %w", err)
 		}
 	}
 
-	if d.db == nil {
+	if d.db == nil && d.connector == nil {
 		return ErrDBNotSet
 	}
+	if d.recycleInterval > 0 && d.source == nil {
+		return ErrDSNFuncNotSet
+	}
+	if d.primary != nil && (d.recycleInterval > 0 || d.poolInterval > 0 || d.checkInterval > 0) {
+		return ErrInvalidReplica
+	}
+	if d.source != nil {
+		d.source.recycle = d.recycleInterval > 0
+	}
+	stats, err := newStats(d.id, d.slowQuery, d.queryMetrics, d.poolInterval > 0)
+	if err != nil {
+		return err
+	}
+	d.stats = stats
+	if d.primary != nil {
+		if d.shutdownTimeout == 0 {
+			d.shutdownTimeout = d.primary.shutdownTimeout
+		}
+		if d.stats == nil {
+			d.stats = d.primary.stats
+		}
+	}
+	if d.connector != nil {
+		if err := d.open(); err != nil {
+			return err
+		}
+	}
+	if d.health, err = newHealth(d.id); err != nil {
+		return errors.Join(err, d.db.Close())
+	}
+	for _, fn := range d.pool {
+		fn(d.db)
+	}
+
+	if d.pingTimeout > 0 {
+		if err := d.ping(); err != nil {
+			return errors.Join(err, d.db.Close())
+		}
+	}
 
+	if d.migrations != nil {
+		opts := d.migrateOpts
+		if slices.Contains(dollarDrivers, d.driverName) {
+			opts = append([]MigrateOpt{WithMigrationDollarPlaceholders()}, opts...)
+		}
+		d.migrator = NewMigrator(d.db, d.migrations, opts...)
+		if _, err := d.migrator.Up(context.Background()); err != nil {
+			return errors.Join(err, d.db.Close())
+		}
+	}
+
+	if err := d.initReplicas(); err != nil {
+		return errors.Join(fmt.Errorf("replica: %w", err), d.db.Close())
+	}
 	return nil
 }
 
-func (d *DB) Run() error {
-	<-d.done
+// open opens the db from the connector set by WithConnector. The connector
+// is wrapped only when queries must be tracked for WithShutdownTimeout or
+// query statistics, or connections recycled for WithRecycleOnChange, so that
+// sql.Conn.Raw hands out the driver connection otherwise.
+func (d *DB) open() error {
+	c := d.connector
+	if d.shutdownTimeout > 0 || d.stats.tracksQueries() || d.recycleInterval > 0 {
+		c = &connector{Connector: c, db: d}
+	}
+	if !d.otel {
+		d.db = sql.OpenDB(c)
+		return nil
+	}
+	db := otelsql.OpenDB(c, d.otelOpts...)
+	if _, err := otelsql.RegisterDBStatsMetrics(db, d.otelOpts...); err != nil {
+		return errors.Join(err, db.Close())
+	}
+	d.db = db
 	return nil
 }
 
+// ping pings the database with exponential backoff until it succeeds or
+// the ping timeout passes.
+func (d *DB) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.pingTimeout)
+	defer cancel()
+	backoff := d.pingMin
+	for {
+		err := d.db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+			backoff = min(backoff*2, d.pingMax)
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w: %w", ErrPingFailed, err)
+		}
+	}
+}
+
+// Run checks the health of the database and its replicas, checks for
+// changed credentials and records pool metrics until Stop is called.
+func (d *DB) Run() error {
+	var health, recycle, pool, check <-chan time.Time
+	if len(d.replicas) > 0 {
+		t := time.NewTicker(d.healthInterval)
+		defer t.Stop()
+		health = t.C
+	}
+	if d.source != nil && d.recycleInterval > 0 {
+		t := time.NewTicker(d.recycleInterval)
+		defer t.Stop()
+		recycle = t.C
+	}
+	if d.poolInterval > 0 {
+		t := time.NewTicker(d.poolInterval)
+		defer t.Stop()
+		pool = t.C
+	}
+	if d.checkInterval > 0 {
+		t := time.NewTicker(d.checkInterval)
+		defer t.Stop()
+		check = t.C
+	}
+	for {
+		select {
+		case <-d.done:
+			return nil
+		case <-health:
+			d.checkReplicas()
+		case <-recycle:
+			// Errors show up when the next connection is opened.
+			_ = d.source.refresh(context.Background())
+		case <-pool:
+			stats := d.db.Stats()
+			d.stats.pool(context.Background(), stats.WaitCount, stats.WaitDuration)
+		case <-check:
+			// Failures are reported by check.
+			_ = d.check(context.Background(), true)
+		}
+	}
+}
+
+// Stop closes the primary and every replica, waiting for queries in flight
+// as set by WithShutdownTimeout. Queries run through Querier are canceled
+// right away.
 func (d *DB) Stop() error {
 	defer close(d.done)
-	return d.db.Close()
+	d.cancelQueries()
+	d.health.stop()
+	errs := make([]error, len(d.replicas)+1)
+	wg := sync.WaitGroup{}
+	for i, r := range d.replicas {
+		wg.Go(func() { errs[i+1] = r.db.Stop() })
+	}
+	if aborted := d.track.stop(d.shutdownTimeout); aborted > 0 {
+		errs[0] = fmt.Errorf("%w: %d in flight", ErrQueriesAborted, aborted)
+	}
+	errs[0] = errors.Join(errs[0], d.db.Close())
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func (d *DB) ID() string { return ID }
+// ID returns the ID set by WithID, or ID by default.
+func (d *DB) ID() string {
+	if d.id == "" {
+		return ID
+	}
+	return d.id
+}
 
 // DB returns the underlying *sql.DB. Only valid after Init has run.
 func (d *DB) DB() *sql.DB { return d.db }
 
+// Migrator returns the migrator set up by WithMigrations, or nil without
+// migrations. Only valid after Init has run.
+func (d *DB) Migrator() *Migrator { return d.migrator }
+
 type Opt func(*DB) error
 
+// WithID sets the ID of the module, so that several databases can be told
+// apart in srvc errors, logs and metrics, which have it as the sqlmod.id
+// attribute.
+func WithID(id string) Opt {
+	return func(d *DB) error {
+		if id == "" {
+			return ErrInvalidID
+		}
+		d.id = id
+		return nil
+	}
+}
+
 // WithDSN opens a *sql.DB from the given driver name and DSN.
 func WithDSN(driver, dsn string) Opt {
 	return func(d *DB) error {
-		db, err := sql.Open(driver, dsn)
+		c, err := openConnector(driver, dsn)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrFailedOpenDB, err)
+			return err
 		}
-		d.db = db
+		d.setConnector(c, driver, false, nil)
+		return nil
+	}
+}
+
+// WithConnector opens a *sql.DB using the given connector, for example one
+// created by the driver with custom configuration.
+func WithConnector(c driver.Connector) Opt {
+	return func(d *DB) error {
+		d.setConnector(c, "", false, nil)
 		return nil
 	}
 }
 
+// setConnector sets the connector the db is opened from once all options
+// are applied, see open. driverName is empty if unknown.
+func (d *DB) setConnector(c driver.Connector, driverName string, otel bool, opts []otelsql.Option) {
+	d.db, d.connector, d.driverName, d.otel, d.otelOpts = nil, c, driverName, otel, opts
+}
+
+// openConnector returns the connector of a registered driver for dsn.
+func openConnector(driverName, dsn string) (driver.Connector, error) {
+	// Opening the db doesn't connect, it only looks up the driver.
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedOpenDB, err)
+	}
+	drv := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFailedOpenDB, err)
+	}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFailedOpenDB, err)
+		}
+		return c, nil
+	}
+	return dsnConnector{dsn: dsn, drv: drv}, nil
+}
+
 // WithDB sets *sql.DB for module.
 func WithDB(db *sql.DB) Opt {
 	return WithDBFn(func() (*sql.DB, error) {
@@ -89,7 +375,7 @@ func WithDBFn(fn func() (*sql.DB, error)) Opt {
 		if err != nil {
 			return err
 		}
-		d.db = db
+		d.db, d.connector, d.driverName = db, nil, ""
 		return nil
 	}
 }
@@ -97,16 +383,79 @@ func WithDBFn(fn func() (*sql.DB, error)) Opt {
 // WithOtel creates *sql.DB and instruments it with OpenTelemetry.
 func WithOtel(driver, dsn string, opts ...otelsql.Option) Opt {
 	return func(d *DB) error {
-		db, err := otelsql.Open(driver, dsn, opts...)
+		c, err := openConnector(driver, dsn)
 		if err != nil {
 			return err
 		}
+		d.setConnector(c, driver, true, opts)
+		return nil
+	}
+}
 
-		_, err = otelsql.RegisterDBStatsMetrics(db, opts...)
-		if err != nil {
-			return err
+// WithMaxOpenConns calls sql.DB.SetMaxOpenConns once the db is set.
+func WithMaxOpenConns(n int) Opt {
+	return withPool(func(db *sql.DB) { db.SetMaxOpenConns(n) })
+}
+
+// WithMaxIdleConns calls sql.DB.SetMaxIdleConns once the db is set.
+func WithMaxIdleConns(n int) Opt {
+	return withPool(func(db *sql.DB) { db.SetMaxIdleConns(n) })
+}
+
+// WithConnMaxLifetime calls sql.DB.SetConnMaxLifetime once the db is set.
+func WithConnMaxLifetime(d time.Duration) Opt {
+	return withPool(func(db *sql.DB) { db.SetConnMaxLifetime(d) })
+}
+
+// WithConnMaxIdleTime calls sql.DB.SetConnMaxIdleTime once the db is set.
+func WithConnMaxIdleTime(d time.Duration) Opt {
+	return withPool(func(db *sql.DB) { db.SetConnMaxIdleTime(d) })
+}
+
+// withPool defers pool settings until all options are applied, so they can
+// be given before the option that sets the db.
+func withPool(fn func(*sql.DB)) Opt {
+	return func(d *DB) error {
+		d.pool = append(d.pool, fn)
+		return nil
+	}
+}
+
+// WithPing makes Init ping the database until it responds, so that a wrong
+// DSN fails at startup and a database that is still starting is waited for.
+// Failed pings are retried with exponential backoff and Init fails with
+// ErrPingFailed once timeout has passed.
+func WithPing(timeout time.Duration) Opt {
+	return func(d *DB) error {
+		if timeout <= 0 {
+			return ErrInvalidPing
 		}
+		d.pingTimeout = timeout
+		return nil
+	}
+}
 
-		return WithDB(db)(d)
+// WithPingBackoff sets the delay before the first ping retry, doubled after
+// each failure up to maxDelay. Default is 100ms growing up to 5s.
+func WithPingBackoff(initial, maxDelay time.Duration) Opt {
+	return func(d *DB) error {
+		if initial <= 0 || maxDelay < initial {
+			return ErrInvalidPing
+		}
+		d.pingMin, d.pingMax = initial, maxDelay
+		return nil
+	}
+}
+
+// WithMigrations applies pending migrations from fsys during Init, after
+// the ping set by WithPing. See Migrator for the file layout and locking.
+// Init fails with ErrMigrationFailed if a migration fails. Postgres drivers
+// opened by name get WithMigrationDollarPlaceholders, while it must be given
+// for databases set with WithConnector or WithDB.
+func WithMigrations(fsys fs.FS, opts ...MigrateOpt) Opt {
+	return func(d *DB) error {
+		d.migrations = fsys
+		d.migrateOpts = opts
+		return nil
 	}
 }
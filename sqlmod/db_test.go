@@ -1,16 +1,24 @@
 package sqlmod_test
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/XSAM/otelsql"
 	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
 
 	_ "github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
 func TestDB(t *testing.T) {
@@ -29,6 +37,36 @@ func TestDB(t *testing.T) {
 	require.Equal(t, "sqlmod", dbx.ID())
 }
 
+func TestDB_UnwrapConn(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []sqlmod.Opt
+		wrapped bool
+	}{
+		{name: "DSN", opts: []sqlmod.Opt{sqlmod.WithDSN("sqlite3", ":memory:")}},
+		{name: "Tracked", opts: []sqlmod.Opt{sqlmod.WithDSN("sqlite3", ":memory:"), sqlmod.WithShutdownTimeout(time.Second)}, wrapped: true},
+		{name: "Stats", opts: []sqlmod.Opt{sqlmod.WithQueryMetrics(), sqlmod.WithDSN("sqlite3", ":memory:")}, wrapped: true},
+		{name: "Otel", opts: []sqlmod.Opt{sqlmod.WithOtel("sqlite3", ":memory:")}, wrapped: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dbx := sqlmod.New(tt.opts...)
+			require.NoError(t, dbx.Init())
+			t.Cleanup(func() { require.NoError(t, dbx.Stop()) })
+
+			conn, err := dbx.DB().Conn(context.Background())
+			require.NoError(t, err)
+			defer conn.Close()
+			require.NoError(t, conn.Raw(func(dc any) error {
+				_, raw := dc.(*sqlite3.SQLiteConn)
+				require.Equal(t, !tt.wrapped, raw)
+				require.IsType(t, &sqlite3.SQLiteConn{}, sqlmod.UnwrapConn(dc))
+				return nil
+			}))
+		})
+	}
+	require.Equal(t, "conn", sqlmod.UnwrapConn("conn"))
+}
+
 func TestDB_ErrFailedOpenDB(t *testing.T) {
 	dbx := sqlmod.New(
 		sqlmod.WithDSN("not valid driver", ""),
@@ -47,3 +85,90 @@ func TestDB_WithOtel(t *testing.T) {
 	)
 	require.NoError(t, dbx.Init())
 }
+
+func TestDB_PoolOptions(t *testing.T) {
+	dbx := sqlmod.New(
+		sqlmod.WithMaxOpenConns(3),
+		sqlmod.WithMaxIdleConns(2),
+		sqlmod.WithConnMaxLifetime(time.Minute),
+		sqlmod.WithConnMaxIdleTime(time.Second),
+		sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db")),
+		sqlmod.WithPing(time.Second),
+	)
+	require.NoError(t, dbx.Init())
+	require.Equal(t, 3, dbx.DB().Stats().MaxOpenConnections)
+	require.Equal(t, 1, dbx.DB().Stats().Idle, "ping connection returned to pool")
+
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+	require.NoError(t, dbx.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestDB_PingRetry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		conn := &flakyConnector{failures: 3}
+		start := time.Now()
+		dbx := sqlmod.New(
+			sqlmod.WithDB(sql.OpenDB(conn)),
+			sqlmod.WithPing(time.Minute),
+			sqlmod.WithPingBackoff(time.Second, 3*time.Second),
+		)
+		require.NoError(t, dbx.Init())
+		require.Equal(t, 4, conn.attempts)
+		require.Equal(t, 6*time.Second, time.Since(start), "waited 1s, 2s and 3s")
+		require.NoError(t, dbx.DB().Close())
+	})
+}
+
+func TestDB_PingTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		conn := &flakyConnector{failures: -1}
+		start := time.Now()
+		dbx := sqlmod.New(
+			sqlmod.WithDB(sql.OpenDB(conn)),
+			sqlmod.WithPing(10*time.Second),
+		)
+		err := dbx.Init()
+		require.ErrorIs(t, err, sqlmod.ErrPingFailed)
+		require.ErrorIs(t, err, errConnect)
+		require.Equal(t, 10*time.Second, time.Since(start))
+		require.Error(t, dbx.DB().Ping(), "db is closed")
+	})
+}
+
+func TestDB_InvalidPing(t *testing.T) {
+	for _, opt := range []sqlmod.Opt{
+		sqlmod.WithPing(0),
+		sqlmod.WithPingBackoff(0, time.Second),
+		sqlmod.WithPingBackoff(time.Second, time.Millisecond),
+	} {
+		dbx := sqlmod.New(sqlmod.WithDB(&sql.DB{}), opt)
+		require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidPing)
+	}
+}
+
+var errConnect = errors.New("connection refused")
+
+// flakyConnector fails given number of connection attempts, or every
+// attempt when failures is negative.
+type flakyConnector struct {
+	failures int
+	attempts int
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.failures < 0 || c.attempts <= c.failures {
+		return nil, errConnect
+	}
+	return fakeConn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.ErrUnsupported }
@@ -0,0 +1,201 @@
+package sqlmod
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	ErrUnhealthy    = errStr("db unhealthy")
+	ErrInvalidCheck = errStr("check timeout, cache duration and interval must be positive")
+)
+
+// health holds the last result of Check and reports changes between
+// healthy and unhealthy.
+type health struct {
+	id      string
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+	stopped bool
+	// call is the check in flight shared by concurrent callers.
+	call *healthCall
+
+	logger      log.Logger
+	transitions metric.Int64Counter
+}
+
+func newHealth(id string) (*health, error) {
+	transitions, err := otel.Meter(instrumentationName).Int64Counter("sqlmod.health.transitions",
+		metric.WithDescription("Number of changes between healthy and unhealthy."),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	return &health{id: id, logger: global.Logger(instrumentationName), transitions: transitions}, nil
+}
+
+// Check pings the database, or runs the query set by WithCheckQuery, and
+// returns an error wrapping ErrUnhealthy if it fails or takes longer than
+// the timeout set by WithCheckTimeout. With WithCheckCache the last result
+// is returned while it is fresh. Concurrent calls share the check in
+// flight, and each returns early once its ctx is done. The signature fits
+// readiness handlers and sigmod.WithLivenessCheck. Only valid after Init
+// has run.
+func (d *DB) Check(ctx context.Context) error {
+	return d.check(ctx, false)
+}
+
+type healthCall struct {
+	done chan struct{}
+	err  error
+}
+
+func (d *DB) check(ctx context.Context, force bool) error {
+	h := d.health
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return fmt.Errorf("%w: %w", ErrUnhealthy, ErrDBStopped)
+	}
+	if !force && d.checkCache > 0 && !h.checked.IsZero() && time.Since(h.checked) < d.checkCache {
+		err := h.err
+		h.mu.Unlock()
+		return err
+	}
+	c := h.call
+	if c == nil {
+		c = &healthCall{done: make(chan struct{})}
+		h.call = c
+		// The check is shared, so it must not fail because one caller gives up.
+		go d.runCheck(context.WithoutCancel(ctx), c)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrUnhealthy, ctx.Err())
+	}
+}
+
+// runCheck queries the database and stores the result in c and the cache.
+func (d *DB) runCheck(ctx context.Context, c *healthCall) {
+	ctx, cancel := context.WithTimeout(ctx, d.checkTimeout)
+	defer cancel()
+	var err error
+	if d.checkQuery == "" {
+		err = d.db.PingContext(ctx)
+	} else {
+		_, err = d.db.ExecContext(ctx, d.checkQuery)
+	}
+
+	h := d.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	first := h.checked.IsZero()
+	if (first && err != nil) || (!first && (err == nil) != (h.err == nil)) {
+		h.report(ctx, err)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrUnhealthy, err)
+	}
+	h.checked, h.err = time.Now(), err
+	h.call, c.err = nil, err
+	close(c.done)
+}
+
+// stop waits for a check in progress and makes later checks fail without
+// reporting the closed pool as a change.
+func (h *health) stop() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.stopped = true
+	c := h.call
+	h.mu.Unlock()
+	if c != nil {
+		<-c.done
+	}
+}
+
+// report logs and counts a change to the result of a check failing with err.
+func (h *health) report(ctx context.Context, err error) {
+	// The check context may have expired, which must not drop the report.
+	ctx = context.WithoutCancel(ctx)
+	h.transitions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("sqlmod.id", h.id),
+		attribute.Bool("db.healthy", err == nil),
+	))
+
+	r := log.Record{}
+	r.SetTimestamp(time.Now())
+	r.AddAttributes(log.String("sqlmod.id", h.id))
+	if err == nil {
+		r.SetSeverity(log.SeverityInfo)
+		r.SetSeverityText("INFO")
+		r.SetBody(log.StringValue("Database healthy"))
+	} else {
+		r.SetSeverity(log.SeverityError)
+		r.SetSeverityText("ERROR")
+		r.SetBody(log.StringValue("Database unhealthy"))
+		r.AddAttributes(log.String("error", err.Error()))
+	}
+	h.logger.Emit(ctx, r)
+}
+
+// WithCheckTimeout sets how long Check waits for the database. Default is 1s.
+func WithCheckTimeout(timeout time.Duration) Opt {
+	return func(d *DB) error {
+		if timeout <= 0 {
+			return ErrInvalidCheck
+		}
+		d.checkTimeout = timeout
+		return nil
+	}
+}
+
+// WithCheckQuery makes Check run query instead of pinging the database,
+// for example to make sure a table can be read.
+func WithCheckQuery(query string) Opt {
+	return func(d *DB) error {
+		d.checkQuery = query
+		return nil
+	}
+}
+
+// WithCheckCache makes Check return the last result for ttl instead of
+// querying the database on every call, so that frequent probes don't load it.
+func WithCheckCache(ttl time.Duration) Opt {
+	return func(d *DB) error {
+		if ttl <= 0 {
+			return ErrInvalidCheck
+		}
+		d.checkCache = ttl
+		return nil
+	}
+}
+
+// WithCheckInterval runs Check every interval while the module runs, so
+// that changes between healthy and unhealthy are logged through the global
+// OpenTelemetry logger and counted in sqlmod.health.transitions even when
+// nothing else calls Check. Background checks bypass the cache and refresh it.
+func WithCheckInterval(interval time.Duration) Opt {
+	return func(d *DB) error {
+		if interval <= 0 {
+			return ErrInvalidCheck
+		}
+		d.checkInterval = interval
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+package sqlmod_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestCheck(t *testing.T) {
+	c := &pingConnector{}
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithCheckTimeout(10*time.Millisecond))
+	require.NoError(t, dbx.Init())
+	require.NoError(t, dbx.Check(context.Background()))
+
+	c.fail.Store(true)
+	require.ErrorIs(t, dbx.Check(context.Background()), sqlmod.ErrUnhealthy)
+	require.ErrorIs(t, dbx.Check(context.Background()), errPing)
+
+	c.fail.Store(false)
+	c.block.Store(true)
+	err := dbx.Check(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrUnhealthy)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, dbx.Stop())
+}
+
+func TestCheck_Concurrent(t *testing.T) {
+	c := &pingConnector{}
+	c.block.Store(true)
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithCheckTimeout(50*time.Millisecond))
+	require.NoError(t, dbx.Init())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, dbx.Check(ctx), context.DeadlineExceeded, "returns once caller gives up")
+
+	wg := &errgroup.ErrGroup{}
+	start := time.Now()
+	for range 5 {
+		wg.Go(func() error { return dbx.Check(context.Background()) })
+	}
+	require.ErrorIs(t, wg.Wait(), sqlmod.ErrUnhealthy)
+	require.Less(t, time.Since(start), 250*time.Millisecond, "checks don't queue")
+	require.LessOrEqual(t, c.pings.Load(), int64(2), "concurrent checks share the ping")
+	require.NoError(t, dbx.Stop())
+}
+
+func TestCheck_Query(t *testing.T) {
+	dbx := newCounterDB(t, sqlmod.WithCheckQuery(`SELECT n FROM counter`))
+	require.NoError(t, dbx.Check(context.Background()))
+
+	_, err := dbx.DB().Exec(`DROP TABLE counter`)
+	require.NoError(t, err)
+	require.ErrorContains(t, dbx.Check(context.Background()), "no such table")
+}
+
+func TestCheck_Cache(t *testing.T) {
+	c := &pingConnector{}
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithCheckCache(time.Hour))
+	require.NoError(t, dbx.Init())
+	require.NoError(t, dbx.Check(context.Background()))
+	pings := c.pings.Load()
+
+	c.fail.Store(true)
+	require.NoError(t, dbx.Check(context.Background()), "cached result")
+	require.Equal(t, pings, c.pings.Load())
+	require.NoError(t, dbx.Stop())
+}
+
+func TestCheck_Interval(t *testing.T) {
+	p := &recordProcessor{}
+	global.SetLoggerProvider(sdklog.NewLoggerProvider(sdklog.WithProcessor(p)))
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	c := &pingConnector{}
+	dbx := sqlmod.New(
+		sqlmod.WithConnector(c),
+		sqlmod.WithCheckCache(time.Hour),
+		sqlmod.WithCheckInterval(time.Millisecond),
+	)
+	require.NoError(t, dbx.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+
+	require.Eventually(t, func() bool { return c.pings.Load() > 1 }, time.Second, time.Millisecond)
+	require.Empty(t, p.get(), "healthy from the start")
+	c.fail.Store(true)
+	require.Eventually(t, func() bool { return dbx.Check(context.Background()) != nil }, time.Second, time.Millisecond)
+	c.fail.Store(false)
+	require.Eventually(t, func() bool { return dbx.Check(context.Background()) == nil }, time.Second, time.Millisecond)
+	require.NoError(t, dbx.Stop())
+	require.NoError(t, wg.Wait())
+
+	records := p.get()
+	require.Len(t, records, 2)
+	require.Equal(t, "Database unhealthy", records[0].Body().AsString())
+	require.Equal(t, errPing.Error(), attributes(records[0])["error"])
+	require.Equal(t, "Database healthy", records[1].Body().AsString())
+
+	sum := collect(t, reader)["sqlmod.health.transitions"].(metricdata.Sum[int64])
+	transitions := map[bool]int64{}
+	for _, dp := range sum.DataPoints {
+		healthy, _ := dp.Attributes.Value(attribute.Key("db.healthy"))
+		transitions[healthy.AsBool()] = dp.Value
+	}
+	require.Equal(t, map[bool]int64{false: 1, true: 1}, transitions)
+}
+
+func TestCheck_Errors(t *testing.T) {
+	for _, opt := range []sqlmod.Opt{
+		sqlmod.WithCheckTimeout(0),
+		sqlmod.WithCheckCache(-time.Second),
+		sqlmod.WithCheckInterval(0),
+	} {
+		dbx := sqlmod.New(sqlmod.WithDB(nil), opt)
+		require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidCheck)
+	}
+}
+
+var errPing = errors.New("connection refused")
+
+type pingConnector struct {
+	pings atomic.Int64
+	fail  atomic.Bool
+	block atomic.Bool
+}
+
+func (c *pingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &pingConn{c: c}, nil
+}
+
+func (c *pingConnector) Driver() driver.Driver { return nil }
+
+// pingConn fails or blocks pings as set in its connector.
+type pingConn struct {
+	fakeConn
+	c *pingConnector
+}
+
+func (c *pingConn) Ping(ctx context.Context) error {
+	c.c.pings.Add(1)
+	if c.c.block.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if c.c.fail.Load() {
+		return errPing
+	}
+	return nil
+}
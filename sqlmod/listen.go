@@ -0,0 +1,284 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+const ListenerID = "listener"
+
+const (
+	ErrBackendNotSet  = errStr("listen backend not set")
+	ErrInvalidListen  = errStr("listen backoff and poll interval must be positive")
+	ErrUnknownChannel = errStr("no version query for channel")
+)
+
+// Notification is a notification received on a channel.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// ListenBackend delivers notifications of channels, for example with
+// Postgres LISTEN, see package pqlisten.
+type ListenBackend interface {
+	// Listen subscribes to channels and calls fn for each notification until
+	// ctx is done or the connection fails. It must not call fn after it has
+	// returned.
+	Listen(ctx context.Context, channels []string, fn func(Notification)) error
+}
+
+// Listener is a module turning notifications of a ListenBackend into
+// callbacks and Go channels. If the backend fails, it reconnects with
+// exponential backoff and subscribes to all channels again. Notifications
+// sent while reconnecting may be lost, depending on the backend. Place it
+// after the database in srvc.Run.
+type Listener struct {
+	backend ListenBackend
+	opts    []ListenOpt
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	subs    map[string][]func(Notification)
+	chans   []chan Notification
+	changed chan struct{}
+
+	backoffMin time.Duration
+	backoffMax time.Duration
+	log        *slog.Logger
+}
+
+// NewListener creates Listener receiving notifications from backend.
+func NewListener(backend ListenBackend, opts ...ListenOpt) *Listener {
+	return &Listener{
+		backend: backend,
+		opts:    opts,
+		subs:    map[string][]func(Notification){},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+func (l *Listener) Init() error {
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	l.backoffMin, l.backoffMax = 100*time.Millisecond, 10*time.Second
+	l.log = slog.Default()
+	for _, opt := range l.opts {
+		if err := opt(l); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	if l.backend == nil {
+		return ErrBackendNotSet
+	}
+	return nil
+}
+
+// Run listens until Stop is called and then closes the channels returned
+// by Chan.
+func (l *Listener) Run() error {
+	defer l.closeChans()
+	// Channels subscribed to before now are listened to from the start.
+	select {
+	case <-l.changed:
+	default:
+	}
+	backoff := l.backoffMin
+	for {
+		ctx, cancel := context.WithCancel(l.ctx)
+		go func() {
+			select {
+			case <-l.changed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		start := time.Now()
+		err := l.backend.Listen(ctx, l.channels(), l.dispatch)
+		resubscribe := ctx.Err() != nil
+		cancel()
+
+		switch {
+		case l.ctx.Err() != nil:
+			return nil
+		case resubscribe:
+			backoff = l.backoffMin
+			continue
+		case time.Since(start) >= l.backoffMax:
+			// The connection worked for a while, so this is a new failure.
+			backoff = l.backoffMin
+		}
+		l.log.Warn("Listen failed, reconnecting", slog.Duration("backoff", backoff), slog.Any("error", err))
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+			backoff = min(backoff*2, l.backoffMax)
+		case <-l.ctx.Done():
+			t.Stop()
+			return nil
+		}
+	}
+}
+
+// Stop stops listening.
+func (l *Listener) Stop() error {
+	l.cancel()
+	return nil
+}
+
+func (l *Listener) ID() string { return ListenerID }
+
+// Subscribe calls fn for every notification on channel. Callbacks run one
+// at a time, so a slow callback delays the ones after it. Subscribing to a
+// new channel while the listener runs makes it subscribe again.
+func (l *Listener) Subscribe(channel string, fn func(Notification)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.subs[channel]
+	l.subs[channel] = append(l.subs[channel], fn)
+	if !ok {
+		select {
+		case l.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Chan returns a channel with buffer of given size receiving notifications
+// on channel. The listener waits for room in the buffer, so the channel
+// must be read. It is closed once the listener stops.
+func (l *Listener) Chan(channel string, size int) <-chan Notification {
+	ch := make(chan Notification, size)
+	l.mu.Lock()
+	l.chans = append(l.chans, ch)
+	l.mu.Unlock()
+	l.Subscribe(channel, func(n Notification) {
+		select {
+		case ch <- n:
+		case <-l.ctx.Done():
+		}
+	})
+	return ch
+}
+
+func (l *Listener) channels() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Sorted(maps.Keys(l.subs))
+}
+
+func (l *Listener) dispatch(n Notification) {
+	l.mu.Lock()
+	subs := l.subs[n.Channel]
+	l.mu.Unlock()
+	for _, fn := range subs {
+		fn(n)
+	}
+}
+
+func (l *Listener) closeChans() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, ch := range l.chans {
+		close(ch)
+	}
+	l.chans = nil
+}
+
+type ListenOpt func(*Listener) error
+
+// WithListenBackoff sets the delay before reconnecting after the backend
+// fails, doubled after each failure up to maxDelay. Default is 100ms
+// growing up to 10s.
+func WithListenBackoff(initial, maxDelay time.Duration) ListenOpt {
+	return func(l *Listener) error {
+		if initial <= 0 || maxDelay < initial {
+			return ErrInvalidListen
+		}
+		l.backoffMin, l.backoffMax = initial, maxDelay
+		return nil
+	}
+}
+
+// WithListenLogger sets logger for reconnects. Default is slog.Default().
+func WithListenLogger(log *slog.Logger) ListenOpt {
+	return func(l *Listener) error {
+		l.log = log
+		return nil
+	}
+}
+
+// PollBackend is a ListenBackend for databases without LISTEN. It runs a
+// query per channel every interval and notifies the channel when its
+// result changes, with the new result as the payload. The query returns a
+// single value that changes with the data, see VersionQuery. Changes made
+// while the backend reconnects are notified once it succeeds again.
+type PollBackend struct {
+	db       *DB
+	interval time.Duration
+	queries  map[string]string
+
+	mu       sync.Mutex
+	versions map[string]sql.NullString
+}
+
+// NewPollBackend creates PollBackend polling db every interval with
+// queries by channel name.
+func NewPollBackend(db *DB, interval time.Duration, queries map[string]string) *PollBackend {
+	return &PollBackend{db: db, interval: interval, queries: queries, versions: map[string]sql.NullString{}}
+}
+
+// VersionQuery returns a query for PollBackend selecting the greatest value
+// of a version column, for example one increased or set to the current
+// time by every write. Deletes are noticed only if they change it too.
+func VersionQuery(table, column string) string {
+	return "SELECT MAX(" + column + ") FROM " + table
+}
+
+func (b *PollBackend) Listen(ctx context.Context, channels []string, fn func(Notification)) error {
+	if b.interval <= 0 {
+		return ErrInvalidListen
+	}
+	for _, channel := range channels {
+		if _, ok := b.queries[channel]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+		}
+	}
+	t := time.NewTicker(b.interval)
+	defer t.Stop()
+	for {
+		for _, channel := range channels {
+			if err := b.poll(ctx, channel, fn); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// poll notifies channel if its version has changed since the last poll.
+// The first poll of a channel only records the version.
+func (b *PollBackend) poll(ctx context.Context, channel string, fn func(Notification)) error {
+	version := sql.NullString{}
+	if err := b.db.DB().QueryRowContext(ctx, b.queries[channel]).Scan(&version); err != nil {
+		return fmt.Errorf("failed to poll %s: %w", channel, err)
+	}
+	b.mu.Lock()
+	last, ok := b.versions[channel]
+	b.versions[channel] = version
+	b.mu.Unlock()
+	if ok && last != version {
+		fn(Notification{Channel: channel, Payload: version.String})
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sqlmod_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/go-srvc/mods/sqlmod/sqlmodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+var listenMigrations = fstest.MapFS{
+	"1_users.up.sql": {Data: []byte(`CREATE TABLE users (name TEXT NOT NULL, version INTEGER NOT NULL)`)},
+}
+
+func TestListener_Poll(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"), sqlmod.WithMigrations(listenMigrations, sqlmod.WithMigrationLogger(discardLogger)))
+	backend := sqlmod.NewPollBackend(dbx, time.Millisecond, map[string]string{
+		"users": sqlmod.VersionQuery("users", "version"),
+	})
+	l := sqlmod.NewListener(backend)
+	require.NoError(t, l.Init())
+	users := l.Chan("users", 100)
+	called := make(chan sqlmod.Notification, 100)
+	l.Subscribe("users", func(n sqlmod.Notification) { called <- n })
+	wg := &errgroup.ErrGroup{}
+	wg.Go(l.Run)
+
+	// Writes made before the first poll are part of the initial version.
+	version := 0
+	n := sqlmod.Notification{}
+	require.Eventually(t, func() bool {
+		version++
+		_, err := dbx.DB().Exec(`INSERT INTO users VALUES ('alice', ?)`, version)
+		require.NoError(t, err)
+		select {
+		case n = <-users:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	require.Equal(t, "users", n.Channel)
+	require.NotEmpty(t, n.Payload)
+	require.Equal(t, n, <-called)
+
+	require.NoError(t, l.Stop())
+	require.NoError(t, wg.Wait())
+	_, ok := <-users
+	require.False(t, ok, "closed once stopped")
+}
+
+func TestListener_Reconnect(t *testing.T) {
+	b := &fakeBackend{listens: make(chan []string, 10), notify: make(chan sqlmod.Notification)}
+	b.fail.Store(2)
+	l := sqlmod.NewListener(b, sqlmod.WithListenBackoff(time.Millisecond, time.Millisecond), sqlmod.WithListenLogger(discardLogger))
+	require.NoError(t, l.Init())
+	a := l.Chan("a", 0)
+	wg := &errgroup.ErrGroup{}
+	wg.Go(l.Run)
+
+	for range 3 {
+		require.Equal(t, []string{"a"}, <-b.listens, "subscribed again after failures")
+	}
+	b.notify <- sqlmod.Notification{Channel: "a", Payload: "1"}
+	require.Equal(t, "1", (<-a).Payload)
+
+	c := l.Chan("c", 0)
+	require.Equal(t, []string{"a", "c"}, <-b.listens, "subscribed again with new channel")
+	b.notify <- sqlmod.Notification{Channel: "c", Payload: "2"}
+	require.Equal(t, "2", (<-c).Payload)
+
+	require.NoError(t, l.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestPollBackend_Errors(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"))
+	b := sqlmod.NewPollBackend(dbx, time.Millisecond, map[string]string{"users": sqlmod.VersionQuery("users", "version")})
+	noop := func(sqlmod.Notification) {}
+	require.ErrorIs(t, b.Listen(context.Background(), []string{"orders"}, noop), sqlmod.ErrUnknownChannel)
+	require.ErrorContains(t, b.Listen(context.Background(), []string{"users"}, noop), "no such table")
+
+	b = sqlmod.NewPollBackend(dbx, 0, nil)
+	require.ErrorIs(t, b.Listen(context.Background(), nil, noop), sqlmod.ErrInvalidListen)
+}
+
+func TestListener_Errors(t *testing.T) {
+	require.ErrorIs(t, sqlmod.NewListener(nil).Init(), sqlmod.ErrBackendNotSet)
+	l := sqlmod.NewListener(&fakeBackend{}, sqlmod.WithListenBackoff(time.Second, time.Millisecond))
+	require.ErrorIs(t, l.Init(), sqlmod.ErrInvalidListen)
+}
+
+// fakeBackend fails the number of times set in fail and then delivers
+// notifications sent to notify.
+type fakeBackend struct {
+	listens chan []string
+	notify  chan sqlmod.Notification
+	fail    atomic.Int64
+}
+
+func (b *fakeBackend) Listen(ctx context.Context, channels []string, fn func(sqlmod.Notification)) error {
+	b.listens <- channels
+	if b.fail.Add(-1) >= 0 {
+		return errors.New("connection lost")
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-b.notify:
+			fn(n)
+		}
+	}
+}
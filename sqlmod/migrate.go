@@ -0,0 +1,549 @@
+package sqlmod
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	ErrInvalidMigration  = errStr("invalid migration")
+	ErrMigrationFailed   = errStr("migration failed")
+	ErrMigrationLocked   = errStr("failed to acquire migration lock")
+	ErrMigrationLockLost = errStr("migration lock lost")
+	ErrInvalidLock       = errStr("migration lock timeout and lease must be positive")
+	ErrNothingToRevert   = errStr("no applied migrations")
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change read from a pair of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. The down file is
+// optional.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time // Zero for pending migrations.
+}
+
+func (s MigrationStatus) Pending() bool { return s.AppliedAt.IsZero() }
+
+// Migrator applies migrations read from the root of an fs.FS and records
+// applied versions in a table that is created on first use. Use fs.Sub to
+// point it at a directory of an embed.FS.
+//
+// Each migration runs in its own transaction together with the update of
+// the version table, so a failing migration is rolled back on databases
+// with transactional DDL. Up and Down hold a lease on a row of a separate
+// table so that only one replica migrates at a time, and create the version
+// table while holding it. The lease is renewed while migrations run and
+// taken over by other replicas once it expires, so a crashed process
+// doesn't block them. Leases expire based on the clock of each replica, so
+// keep replica clocks in sync.
+type Migrator struct {
+	db          *sql.DB
+	fsys        fs.FS
+	table       string
+	dollar      bool
+	dryRun      bool
+	lockTimeout time.Duration
+	lease       time.Duration
+	log         *slog.Logger
+}
+
+// NewMigrator creates Migrator for db with migrations read from fsys.
+func NewMigrator(db *sql.DB, fsys fs.FS, opts ...MigrateOpt) *Migrator {
+	m := &Migrator{
+		db:          db,
+		fsys:        fsys,
+		table:       "schema_migrations",
+		lockTimeout: time.Minute,
+		lease:       time.Minute,
+		log:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Migrations returns all migrations sorted by version.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidMigration, e.Name(), err)
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d used by %q and %q", ErrInvalidMigration, version, mig.Name, match[2])
+		}
+		body, err := fs.ReadFile(m.fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+		if match[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up file", ErrInvalidMigration, mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// Status returns the status of every migration sorted by version.
+// Applied versions without files are included with an empty name.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		statuses = append(statuses, MigrationStatus{Version: mig.Version, Name: mig.Name, AppliedAt: applied[mig.Version]})
+		delete(applied, mig.Version)
+	}
+	for version, at := range applied {
+		statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: at})
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, nil
+}
+
+// WriteStatus writes the migration status as a table, for example from a
+// status subcommand of the service.
+func (m *Migrator) WriteStatus(ctx context.Context, w io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if !s.Pending() {
+			applied = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return tw.Flush()
+}
+
+// Up applies pending migrations in version order and returns them. In dry
+// run mode the pending migrations are only logged and returned.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	ctx, unlock, err := m.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := slices.DeleteFunc(migrations, func(mig Migration) bool {
+		_, ok := applied[mig.Version]
+		return ok
+	})
+	for i, mig := range pending {
+		if m.dryRun {
+			m.log.Info("Pending migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			continue
+		}
+		err := m.apply(ctx, mig.Up, `INSERT INTO `+m.table+` (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UnixNano())
+		if err != nil {
+			return pending[:i], fmt.Errorf("%w: %d_%s: %w", ErrMigrationFailed, mig.Version, mig.Name, err)
+		}
+		m.log.Info("Applied migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+	}
+	return pending, nil
+}
+
+// Down reverts the latest applied migration using its down file and
+// returns it. In dry run mode the migration is only logged and returned.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return Migration{}, err
+	}
+	ctx, unlock, err := m.start(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer unlock()
+
+	applied, err := m.read(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	if len(applied) == 0 {
+		return Migration{}, ErrNothingToRevert
+	}
+	latest := slices.Max(slices.Collect(maps.Keys(applied)))
+	i := slices.IndexFunc(migrations, func(mig Migration) bool { return mig.Version == latest })
+	if i < 0 || migrations[i].Down == "" {
+		return Migration{}, fmt.Errorf("%w: version %d has no down file", ErrInvalidMigration, latest)
+	}
+	mig := migrations[i]
+	if m.dryRun {
+		m.log.Info("Pending revert", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+		return mig, nil
+	}
+	if err := m.apply(ctx, mig.Down, `DELETE FROM `+m.table+` WHERE version = ?`, mig.Version); err != nil {
+		return Migration{}, fmt.Errorf("%w: %d_%s: %w", ErrMigrationFailed, mig.Version, mig.Name, err)
+	}
+	m.log.Info("Reverted migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+	return mig, nil
+}
+
+// apply runs the migration script and the version table update in a
+// single transaction.
+func (m *Migrator) apply(ctx context.Context, script, record string, args ...any) error {
+	err := func() error {
+		tx, err := m.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+		if _, err := tx.ExecContext(ctx, m.bind(record), args...); err != nil {
+			return errors.Join(err, tx.Rollback())
+		}
+		return tx.Commit()
+	}()
+	if err != nil && errors.Is(context.Cause(ctx), ErrMigrationLockLost) {
+		return errors.Join(err, ErrMigrationLockLost)
+	}
+	return err
+}
+
+// start takes the lock and creates the version table while holding it, or
+// does nothing in dry run mode so that the schema is left untouched. The
+// returned context is canceled if the lock is lost.
+func (m *Migrator) start(ctx context.Context) (context.Context, func(), error) {
+	if m.dryRun {
+		return ctx, func() {}, nil
+	}
+	ctx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.ensure(ctx); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return ctx, unlock, nil
+}
+
+// read returns the applied versions for Up and Down, which have created the
+// version table unless in dry run mode.
+func (m *Migrator) read(ctx context.Context) (map[int64]time.Time, error) {
+	if m.dryRun {
+		return m.versions(ctx)
+	}
+	return m.applied(ctx)
+}
+
+// versions returns the applied versions without creating the version table,
+// so if it can't be read while the database is reachable it is taken as not
+// created yet and nothing as applied.
+func (m *Migrator) versions(ctx context.Context) (map[int64]time.Time, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		if pingErr := m.db.PingContext(ctx); pingErr != nil {
+			return nil, errors.Join(err, pingErr)
+		}
+		return map[int64]time.Time{}, nil
+	}
+	return applied, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM `+m.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version, ns int64
+		if err := rows.Scan(&version, &ns); err != nil {
+			return nil, fmt.Errorf("failed to select migrations: %w", err)
+		}
+		applied[version] = time.Unix(0, ns)
+	}
+	return applied, rows.Err()
+}
+
+// ensure creates the version table. It's called while holding the lock.
+func (m *Migrator) ensure(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	return nil
+}
+
+// ensureLock creates the lock table. Replicas starting at the same time
+// may create it concurrently, which fails on some databases like Postgres
+// even with IF NOT EXISTS, so the error is ignored once the table exists.
+func (m *Migrator) ensureLock(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+`_lock (
+		id INTEGER NOT NULL PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err == nil {
+		return nil
+	}
+	n := 0
+	if qErr := m.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+m.table+`_lock`).Scan(&n); qErr != nil {
+		return fmt.Errorf("failed to create table: %w", errors.Join(err, qErr))
+	}
+	return nil
+}
+
+// lock takes the lease, retrying while another process holds it until the
+// lock timeout passes, and renews it until the returned function is called.
+// The returned context is canceled with ErrMigrationLockLost if another
+// process takes the lease over meanwhile.
+func (m *Migrator) lock(ctx context.Context) (context.Context, func(), error) {
+	if m.lockTimeout <= 0 || m.lease <= 0 {
+		return nil, nil, ErrInvalidLock
+	}
+	if err := m.ensureLock(ctx); err != nil {
+		return nil, nil, err
+	}
+	owner := newOwner()
+	if err := m.acquire(ctx, owner); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.renew(ctx, owner, cancel)
+	}()
+	return ctx, func() {
+		cancel(nil)
+		<-done
+		release := m.bind(`DELETE FROM ` + m.table + `_lock WHERE id = 1 AND owner = ?`)
+		if _, err := m.db.ExecContext(context.WithoutCancel(ctx), release, owner); err != nil {
+			m.log.Error("Failed to release migration lock", slog.Any("error", err))
+		}
+	}, nil
+}
+
+// acquire retries tryLock until it succeeds or the lock timeout passes.
+func (m *Migrator) acquire(ctx context.Context, owner string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+	defer cancel()
+	for {
+		ok, err := m.tryLock(ctx, owner)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return fmt.Errorf("%w: %s_lock: %w", ErrMigrationLocked, m.table, err)
+		case err != nil:
+			return err
+		case ok:
+			return nil
+		}
+		t := time.NewTimer(100 * time.Millisecond)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w: %s_lock held by another process", ErrMigrationLocked, m.table)
+		}
+	}
+}
+
+// renew extends the lease every third of it until ctx is done. Failed
+// renewals are retried until the lease is taken over by another process.
+func (m *Migrator) renew(ctx context.Context, owner string, cancel context.CancelCauseFunc) {
+	t := time.NewTicker(m.lease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ok, err := m.tryLock(ctx, owner)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				m.log.Warn("Failed to renew migration lock", slog.Any("error", err))
+			case err == nil && !ok:
+				m.log.Error("Migration lock lost", slog.String("owner", owner))
+				cancel(ErrMigrationLockLost)
+				return
+			}
+		}
+	}
+}
+
+// tryLock takes or extends the lease for owner unless another owner holds
+// an unexpired one. Other errors are returned right away.
+func (m *Migrator) tryLock(ctx context.Context, owner string) (bool, error) {
+	update := m.bind(`UPDATE ` + m.table + `_lock SET owner = ?, expires_at = ? WHERE id = 1 AND (owner = ? OR expires_at <= ?)`)
+	insert := m.bind(`INSERT INTO ` + m.table + `_lock (id, owner, expires_at) VALUES (1, ?, ?)`)
+	for retried := false; ; retried = true {
+		now := time.Now()
+		expires := now.Add(m.lease).UnixNano()
+		res, err := m.db.ExecContext(ctx, update, owner, expires, owner, now.UnixNano())
+		if err != nil {
+			return false, fmt.Errorf("failed to update %s_lock row: %w", m.table, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return false, err
+		} else if n > 0 {
+			return true, nil
+		}
+
+		_, err = m.db.ExecContext(ctx, insert, owner, expires)
+		if err == nil {
+			return true, nil
+		}
+		// Insert fails on the primary key when someone else holds the lease.
+		var holder string
+		qErr := m.db.QueryRowContext(ctx, m.bind(`SELECT owner FROM `+m.table+`_lock WHERE id = 1`)).Scan(&holder)
+		switch {
+		case qErr == nil && holder != owner:
+			return false, nil
+		case errors.Is(qErr, sql.ErrNoRows) && !retried:
+			// The holder released the lease after the update.
+			continue
+		}
+		return false, fmt.Errorf("failed to insert %s_lock row: %w", m.table, errors.Join(err, qErr))
+	}
+}
+
+// newOwner returns an ID of the process telling leases apart.
+func newOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), rand.Text()[:8])
+}
+
+// bind rewrites ? placeholders to $n when needed.
+func (m *Migrator) bind(query string) string {
+	return bind(query, m.dollar)
+}
+
+// bind rewrites ? placeholders to $n if dollar is set.
+func bind(query string, dollar bool) string {
+	if !dollar {
+		return query
+	}
+	b := strings.Builder{}
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type MigrateOpt func(*Migrator)
+
+// WithMigrationTable sets the table recording applied migrations. The lock
+// table gets the same name with a _lock suffix. Default is schema_migrations.
+func WithMigrationTable(name string) MigrateOpt {
+	return func(m *Migrator) { m.table = name }
+}
+
+// WithMigrationDollarPlaceholders uses $1 style placeholders required by
+// Postgres.
+func WithMigrationDollarPlaceholders() MigrateOpt {
+	return func(m *Migrator) { m.dollar = true }
+}
+
+// WithMigrationDryRun logs pending migrations instead of applying them.
+// Nothing is written, so the tables aren't created and no lock is taken.
+func WithMigrationDryRun() MigrateOpt {
+	return func(m *Migrator) { m.dryRun = true }
+}
+
+// WithMigrationLockTimeout sets how long to wait for another process
+// holding the migration lock. Default is one minute. Up and Down fail with
+// ErrInvalidLock if it isn't positive.
+func WithMigrationLockTimeout(d time.Duration) MigrateOpt {
+	return func(m *Migrator) { m.lockTimeout = d }
+}
+
+// WithMigrationLease sets for how long the migration lock is held without
+// renewal, which is how long a crashed process blocks others. The lease is
+// renewed every third of it. Default is one minute. Up and Down fail with
+// ErrInvalidLock if it isn't positive.
+func WithMigrationLease(d time.Duration) MigrateOpt {
+	return func(m *Migrator) { m.lease = d }
+}
+
+// WithMigrationLogger sets logger for applied and pending migrations.
+// Default is slog.Default().
+func WithMigrationLogger(l *slog.Logger) MigrateOpt {
+	return func(m *Migrator) { m.log = l }
+}
@@ -0,0 +1,266 @@
+package sqlmod_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var migrations = fstest.MapFS{
+	"0001_create_users.up.sql":   {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)},
+	"0001_create_users.down.sql": {Data: []byte(`DROP TABLE users;`)},
+	"0002_add_email.up.sql": {Data: []byte(`
+		ALTER TABLE users ADD COLUMN email TEXT;
+		CREATE UNIQUE INDEX users_email ON users (email);
+	`)},
+	"0002_add_email.down.sql": {Data: []byte(`
+		DROP INDEX users_email;
+		ALTER TABLE users DROP COLUMN email;
+	`)},
+	"README.md": {Data: []byte("ignored")},
+}
+
+func TestMigrations(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("sqlite3", dsn),
+		sqlmod.WithMigrations(migrations, sqlmod.WithMigrationLogger(discardLogger)),
+	)
+	require.NoError(t, dbx.Init())
+	_, err := dbx.DB().Exec(`INSERT INTO users (name, email) VALUES ('alice', 'alice@example.com')`)
+	require.NoError(t, err)
+
+	statuses, err := dbx.Migrator().Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.Equal(t, "create_users", statuses[0].Name)
+	require.Equal(t, "add_email", statuses[1].Name)
+	require.False(t, statuses[1].Pending())
+	require.WithinDuration(t, time.Now(), statuses[1].AppliedAt, time.Minute)
+	require.NoError(t, dbx.Stop())
+
+	// Migrations are applied only once.
+	dbx = sqlmod.New(
+		sqlmod.WithDSN("sqlite3", dsn),
+		sqlmod.WithMigrations(migrations, sqlmod.WithMigrationLogger(discardLogger)),
+	)
+	require.NoError(t, dbx.Init())
+	applied, err := dbx.Migrator().Up(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	reverted, err := dbx.Migrator().Down(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reverted.Version)
+	statuses, err = dbx.Migrator().Status(context.Background())
+	require.NoError(t, err)
+	require.True(t, statuses[1].Pending())
+	require.NoError(t, dbx.Stop())
+}
+
+func TestMigrations_DollarDriver(t *testing.T) {
+	registerDollarDriver()
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("ql", filepath.Join(t.TempDir(), "test.db")),
+		sqlmod.WithMigrations(migrations, sqlmod.WithMigrationLogger(discardLogger)),
+	)
+	require.NoError(t, dbx.Init())
+	statuses, err := dbx.Migrator().Status(context.Background())
+	require.NoError(t, err)
+	require.False(t, statuses[1].Pending())
+	require.NoError(t, dbx.Stop())
+}
+
+// registerDollarDriver registers SQLite as ql, one of the drivers using $1
+// placeholders, and makes it reject ? placeholders.
+var registerDollarDriver = sync.OnceFunc(func() { sql.Register("ql", dollarDriver{}) })
+
+type dollarDriver struct{}
+
+func (dollarDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	return dollarConn{Conn: c}, err
+}
+
+// dollarConn hides the optional interfaces of the SQLite connection, so
+// every query goes through Prepare.
+type dollarConn struct{ driver.Conn }
+
+func (c dollarConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "?") {
+		return nil, errors.New("? placeholder")
+	}
+	return c.Conn.Prepare(query)
+}
+
+func TestMigrations_DryRun(t *testing.T) {
+	db := openSQLite(t)
+	out := &bytes.Buffer{}
+	m := sqlmod.NewMigrator(db, migrations,
+		sqlmod.WithMigrationDryRun(),
+		sqlmod.WithMigrationLogger(slog.New(slog.NewTextHandler(out, nil))),
+	)
+	pending, err := m.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Contains(t, out.String(), "name=create_users")
+
+	status := &bytes.Buffer{}
+	require.NoError(t, m.WriteStatus(context.Background(), status))
+	require.Equal(t, "VERSION  NAME          APPLIED AT\n"+
+		"1        create_users  pending\n"+
+		"2        add_email     pending\n", status.String())
+
+	_, err = m.Down(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrNothingToRevert)
+	for _, table := range []string{"users", "schema_migrations", "schema_migrations_lock"} {
+		_, err = db.Exec(`SELECT * FROM ` + table)
+		require.Error(t, err, "%s not created", table)
+	}
+}
+
+func TestMigrations_Failed(t *testing.T) {
+	db := openSQLite(t)
+	m := sqlmod.NewMigrator(db, fstest.MapFS{
+		"1_ok.up.sql":     {Data: []byte(`CREATE TABLE ok (id INTEGER);`)},
+		"2_broken.up.sql": {Data: []byte(`CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES (1);`)},
+	}, sqlmod.WithMigrationLogger(discardLogger))
+
+	applied, err := m.Up(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrMigrationFailed)
+	require.ErrorContains(t, err, "2_broken")
+	require.Len(t, applied, 1)
+	_, err = db.Exec(`SELECT * FROM broken`)
+	require.Error(t, err, "failed migration rolled back")
+
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.False(t, statuses[0].Pending())
+	require.True(t, statuses[1].Pending())
+	_, err = m.Up(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrMigrationFailed, "lock released")
+}
+
+func TestMigrations_Locked(t *testing.T) {
+	db := openSQLite(t)
+	m := sqlmod.NewMigrator(db, migrations,
+		sqlmod.WithMigrationLockTimeout(50*time.Millisecond),
+		sqlmod.WithMigrationLogger(discardLogger),
+	)
+	_, err := db.Exec(lockTable)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schema_migrations_lock (id, owner, expires_at) VALUES (1, 'other', ?)`, time.Now().Add(time.Hour).UnixNano())
+	require.NoError(t, err)
+
+	_, err = m.Up(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrMigrationLocked)
+	_, err = db.Exec(`SELECT * FROM schema_migrations`)
+	require.Error(t, err, "version table created only while holding the lock")
+
+	// A lease left behind by a crashed process is taken over once expired.
+	_, err = db.Exec(`UPDATE schema_migrations_lock SET expires_at = ?`, time.Now().UnixNano())
+	require.NoError(t, err)
+	applied, err := m.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	n := 0
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations_lock`).Scan(&n))
+	require.Zero(t, n, "lock released")
+
+	_, err = db.Exec(`INSERT INTO schema_migrations_lock (id, owner, expires_at) VALUES (1, 'other', ?)`, time.Now().Add(time.Hour).UnixNano())
+	require.NoError(t, err)
+	dbx := sqlmod.New(sqlmod.WithDB(db), sqlmod.WithMigrations(migrations, sqlmod.WithMigrationLockTimeout(50*time.Millisecond)))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrMigrationLocked)
+}
+
+func TestMigrations_LockLost(t *testing.T) {
+	// WAL lets the lease be taken over while the migration reads.
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	_, err = sqlmod.NewMigrator(db, fstest.MapFS{}).Up(context.Background())
+	require.NoError(t, err)
+
+	m := sqlmod.NewMigrator(db, fstest.MapFS{
+		"1_slow.up.sql": {Data: []byte(`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT count(*) FROM c;`)},
+	}, sqlmod.WithMigrationLease(30*time.Millisecond), sqlmod.WithMigrationLogger(discardLogger))
+	upErr := make(chan error)
+	go func() {
+		_, err := m.Up(context.Background())
+		upErr <- err
+	}()
+
+	// The lease is renewed while the migration runs, until another process
+	// takes it over.
+	require.Eventually(t, func() bool {
+		n := 0
+		return db.QueryRow(`SELECT COUNT(*) FROM schema_migrations_lock`).Scan(&n) == nil && n > 0
+	}, time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	_, err = db.Exec(`UPDATE schema_migrations_lock SET owner = 'other'`)
+	require.NoError(t, err)
+	err = <-upErr
+	require.ErrorIs(t, err, sqlmod.ErrMigrationFailed)
+	require.ErrorIs(t, err, sqlmod.ErrMigrationLockLost)
+	n := 0
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations_lock WHERE owner = 'other'`).Scan(&n))
+	require.Equal(t, 1, n, "lease of other owner kept")
+}
+
+func TestMigrations_InvalidLock(t *testing.T) {
+	m := sqlmod.NewMigrator(openSQLite(t), migrations, sqlmod.WithMigrationLease(0))
+	_, err := m.Up(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrInvalidLock)
+}
+
+func TestMigrations_LockError(t *testing.T) {
+	db := openSQLite(t)
+	_, err := db.Exec(`CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY, owner TEXT NOT NULL, expires_at BIGINT NOT NULL, extra TEXT NOT NULL)`)
+	require.NoError(t, err)
+
+	m := sqlmod.NewMigrator(db, migrations, sqlmod.WithMigrationLockTimeout(time.Minute))
+	start := time.Now()
+	_, err = m.Up(context.Background())
+	require.ErrorContains(t, err, "NOT NULL constraint failed")
+	require.NotErrorIs(t, err, sqlmod.ErrMigrationLocked)
+	require.Less(t, time.Since(start), time.Second, "not retried")
+}
+
+func TestMigrations_Invalid(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"MissingUp": {"1_a.down.sql": {}},
+		"EmptyUp":   {"1_a.up.sql": {}},
+		"Duplicate": {"1_a.up.sql": {Data: []byte("SELECT 1")}, "01_b.up.sql": {Data: []byte("SELECT 1")}},
+		"Overflow":  {"99999999999999999999_a.up.sql": {Data: []byte("SELECT 1")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := sqlmod.NewMigrator(nil, fsys).Migrations()
+			require.ErrorIs(t, err, sqlmod.ErrInvalidMigration)
+		})
+	}
+}
+
+const lockTable = `CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY, owner TEXT NOT NULL, expires_at BIGINT NOT NULL)`
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
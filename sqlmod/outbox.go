@@ -0,0 +1,175 @@
+package sqlmod
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Execer is implemented by *sql.Tx, *sql.DB and their sqlx counterparts.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// OutboxMessage is a message enqueued to the outbox.
+type OutboxMessage struct {
+	ID       string
+	Topic    string
+	Payload  []byte
+	Attempts int // Failed attempts to publish so far.
+}
+
+// Outbox writes messages to an outbox table in the transaction of the
+// change they describe, so that a message is stored if and only if the
+// change is committed. Relay publishes them afterwards.
+//
+// The table is not created by Outbox and must be added with migrations.
+// For Postgres:
+//
+//	CREATE TABLE outbox (
+//		id              TEXT PRIMARY KEY,
+//		topic           TEXT NOT NULL,
+//		payload         BYTEA NOT NULL,
+//		created_at      BIGINT NOT NULL,
+//		attempts        INTEGER NOT NULL DEFAULT 0,
+//		next_attempt_at BIGINT NOT NULL,
+//		delivered_at    BIGINT,
+//		last_error      TEXT
+//	);
+//	CREATE INDEX outbox_pending ON outbox (next_attempt_at) WHERE delivered_at IS NULL;
+//
+// Times are stored as Unix nanoseconds.
+type Outbox struct {
+	table  string
+	dollar bool
+}
+
+// NewOutbox creates Outbox with given options.
+func NewOutbox(opts ...OutboxOpt) *Outbox {
+	o := &Outbox{table: "outbox"}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Enqueue writes a message with given topic and payload using tx, which
+// should be the transaction making the change the message describes.
+func (o *Outbox) Enqueue(ctx context.Context, tx Execer, topic string, payload []byte) error {
+	now := outboxClock.next()
+	_, err := tx.ExecContext(ctx, o.bind(`INSERT INTO `+o.table+
+		` (id, topic, payload, created_at, attempts, next_attempt_at) VALUES (?, ?, ?, ?, 0, ?)`),
+		rand.Text(), topic, payload, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	return nil
+}
+
+// pendingMessage is a message due for publishing along with its due time,
+// which identifies the claim on it.
+type pendingMessage struct {
+	OutboxMessage
+	due int64
+}
+
+// pending returns up to limit messages that are due for publishing.
+func (o *Outbox) pending(ctx context.Context, db *sql.DB, limit int) ([]pendingMessage, error) {
+	rows, err := db.QueryContext(ctx, o.bind(`SELECT id, topic, payload, attempts, next_attempt_at FROM `+o.table+
+		` WHERE delivered_at IS NULL AND next_attempt_at <= ? ORDER BY created_at, id LIMIT ?`),
+		time.Now().UnixNano(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var msgs []pendingMessage
+	for rows.Next() {
+		m := pendingMessage{}
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Payload, &m.Attempts, &m.due); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// claim moves the due time of msg to until if nobody has changed it since
+// it was read, so that other relays skip the message while it's published.
+// It reports false if another relay claimed the message first.
+func (o *Outbox) claim(ctx context.Context, db *sql.DB, msg pendingMessage, until int64) (bool, error) {
+	return o.update(ctx, db, `UPDATE `+o.table+
+		` SET next_attempt_at = ? WHERE id = ? AND next_attempt_at = ? AND delivered_at IS NULL`,
+		until, msg.ID, msg.due,
+	)
+}
+
+// release makes a message claimed until claimed due again at due.
+func (o *Outbox) release(ctx context.Context, db *sql.DB, id string, claimed, due int64) error {
+	_, err := o.update(ctx, db, `UPDATE `+o.table+` SET next_attempt_at = ? WHERE id = ? AND next_attempt_at = ?`,
+		due, id, claimed,
+	)
+	return err
+}
+
+func (o *Outbox) delivered(ctx context.Context, db *sql.DB, id string) error {
+	_, err := db.ExecContext(ctx, o.bind(`UPDATE `+o.table+` SET delivered_at = ? WHERE id = ?`),
+		time.Now().UnixNano(), id,
+	)
+	return err
+}
+
+// failed records a failure of a message claimed until claimed, unless
+// another relay has claimed it since.
+func (o *Outbox) failed(ctx context.Context, db *sql.DB, id string, claimed int64, next time.Time, cause error) error {
+	_, err := o.update(ctx, db, `UPDATE `+o.table+
+		` SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ? AND next_attempt_at = ?`,
+		next.UnixNano(), cause.Error(), id, claimed,
+	)
+	return err
+}
+
+// update runs query and reports whether it changed a row.
+func (o *Outbox) update(ctx context.Context, db *sql.DB, query string, args ...any) (bool, error) {
+	res, err := db.ExecContext(ctx, o.bind(query), args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// clock returns increasing Unix nanoseconds, so that messages enqueued by
+// one process keep their order even within the resolution of the clock.
+type clock struct{ last atomic.Int64 }
+
+var outboxClock = &clock{}
+
+func (c *clock) next() int64 {
+	for {
+		last := c.last.Load()
+		now := max(time.Now().UnixNano(), last+1)
+		if c.last.CompareAndSwap(last, now) {
+			return now
+		}
+	}
+}
+
+func (o *Outbox) bind(query string) string { return bind(query, o.dollar) }
+
+type OutboxOpt func(*Outbox)
+
+// WithOutboxTable sets the outbox table. Default is outbox.
+func WithOutboxTable(name string) OutboxOpt {
+	return func(o *Outbox) { o.table = name }
+}
+
+// WithOutboxDollarPlaceholders uses $1 style placeholders required by
+// Postgres.
+func WithOutboxDollarPlaceholders() OutboxOpt {
+	return func(o *Outbox) { o.dollar = true }
+}
@@ -0,0 +1,197 @@
+package sqlmod_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/go-srvc/mods/sqlmod/sqlmodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+var outboxMigrations = fstest.MapFS{
+	"1_outbox.up.sql": {Data: []byte(`CREATE TABLE events (
+		id              TEXT PRIMARY KEY,
+		topic           TEXT NOT NULL,
+		payload         BLOB NOT NULL,
+		created_at      BIGINT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at BIGINT NOT NULL,
+		delivered_at    BIGINT,
+		last_error      TEXT
+	)`)},
+}
+
+func TestOutbox(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"), sqlmod.WithMigrations(outboxMigrations, sqlmod.WithMigrationLogger(discardLogger)))
+	outbox := sqlmod.NewOutbox(sqlmod.WithOutboxTable("events"))
+	ctx := context.Background()
+
+	require.NoError(t, dbx.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		require.NoError(t, outbox.Enqueue(ctx, tx, "users", []byte("created")))
+		return outbox.Enqueue(ctx, tx, "users", []byte("updated"))
+	}))
+	errRollback := errors.New("rollback")
+	require.ErrorIs(t, dbx.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		require.NoError(t, outbox.Enqueue(ctx, tx, "users", []byte("lost")))
+		return errRollback
+	}), errRollback)
+
+	pub := &recordPublisher{}
+	relay := sqlmod.NewRelay(dbx, outbox, pub, sqlmod.WithRelayInterval(time.Millisecond), sqlmod.WithRelayBatchSize(1))
+	require.NoError(t, relay.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(relay.Run)
+	require.Eventually(t, func() bool { return len(pub.get()) == 2 }, time.Second, time.Millisecond)
+	require.NoError(t, relay.Stop())
+	require.NoError(t, wg.Wait())
+
+	msgs := pub.get()
+	require.Equal(t, "users", msgs[0].Topic)
+	require.Equal(t, []byte("created"), msgs[0].Payload)
+	require.Equal(t, []byte("updated"), msgs[1].Payload)
+	require.NotEqual(t, msgs[0].ID, msgs[1].ID)
+	require.Equal(t, 0, pending(t, dbx))
+}
+
+func TestRelay_Retry(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"), sqlmod.WithMigrations(outboxMigrations, sqlmod.WithMigrationLogger(discardLogger)))
+	outbox := sqlmod.NewOutbox(sqlmod.WithOutboxTable("events"))
+	require.NoError(t, outbox.Enqueue(context.Background(), dbx.DB(), "users", []byte("created")))
+
+	errBroker := errors.New("broker down")
+	attempts := []int{}
+	mu := sync.Mutex{}
+	pub := sqlmod.PublisherFunc(func(_ context.Context, msg sqlmod.OutboxMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, msg.Attempts)
+		if len(attempts) < 3 {
+			return errBroker
+		}
+		return nil
+	})
+	relay := sqlmod.NewRelay(dbx, outbox, pub,
+		sqlmod.WithRelayInterval(time.Millisecond),
+		sqlmod.WithRelayBackoff(time.Millisecond, 2*time.Millisecond),
+		sqlmod.WithRelayLogger(discardLogger),
+	)
+	require.NoError(t, relay.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(relay.Run)
+	require.Eventually(t, func() bool { return pending(t, dbx) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, relay.Stop())
+	require.NoError(t, wg.Wait())
+
+	require.Equal(t, []int{0, 1, 2}, attempts)
+	lastError := ""
+	require.NoError(t, dbx.DB().QueryRow(`SELECT last_error FROM events`).Scan(&lastError))
+	require.Equal(t, errBroker.Error(), lastError)
+}
+
+func TestRelay_Replicas(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"), sqlmod.WithMigrations(outboxMigrations, sqlmod.WithMigrationLogger(discardLogger)))
+	outbox := sqlmod.NewOutbox(sqlmod.WithOutboxTable("events"))
+	for range 50 {
+		require.NoError(t, outbox.Enqueue(context.Background(), dbx.DB(), "users", []byte("created")))
+	}
+
+	pub := &recordPublisher{}
+	wg := &errgroup.ErrGroup{}
+	var relays []*sqlmod.Relay
+	for range 3 {
+		relay := sqlmod.NewRelay(dbx, outbox, pub, sqlmod.WithRelayInterval(time.Millisecond), sqlmod.WithRelayBatchSize(5), sqlmod.WithRelayLogger(discardLogger))
+		require.NoError(t, relay.Init())
+		wg.Go(relay.Run)
+		relays = append(relays, relay)
+	}
+	require.Eventually(t, func() bool { return pending(t, dbx) == 0 }, 5*time.Second, time.Millisecond)
+	for _, relay := range relays {
+		require.NoError(t, relay.Stop())
+	}
+	require.NoError(t, wg.Wait())
+
+	published := map[string]int{}
+	for _, msg := range pub.get() {
+		published[msg.ID]++
+	}
+	require.Len(t, published, 50)
+	for id, n := range published {
+		require.Equal(t, 1, n, "published once: %s", id)
+	}
+}
+
+func TestRelay_Stop(t *testing.T) {
+	dbx := sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"), sqlmod.WithMigrations(outboxMigrations, sqlmod.WithMigrationLogger(discardLogger)))
+	outbox := sqlmod.NewOutbox(sqlmod.WithOutboxTable("events"))
+	require.NoError(t, outbox.Enqueue(context.Background(), dbx.DB(), "users", []byte("created")))
+
+	started := make(chan struct{})
+	pub := sqlmod.PublisherFunc(func(ctx context.Context, _ sqlmod.OutboxMessage) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	relay := sqlmod.NewRelay(dbx, outbox, pub)
+	require.NoError(t, relay.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(relay.Run)
+	<-started
+	require.NoError(t, relay.Stop())
+	require.NoError(t, wg.Wait())
+
+	attempts := -1
+	require.NoError(t, dbx.DB().QueryRow(`SELECT attempts FROM events`).Scan(&attempts))
+	require.Equal(t, 0, attempts, "canceled publish is not a failure")
+	require.Equal(t, 1, pending(t, dbx))
+}
+
+func TestRelay_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		pub     sqlmod.Publisher
+		opts    []sqlmod.RelayOpt
+		wantErr error
+	}{
+		{name: "Publisher", wantErr: sqlmod.ErrPublisherNotSet},
+		{name: "Interval", pub: &recordPublisher{}, opts: []sqlmod.RelayOpt{sqlmod.WithRelayInterval(0)}, wantErr: sqlmod.ErrInvalidRelay},
+		{name: "Batch", pub: &recordPublisher{}, opts: []sqlmod.RelayOpt{sqlmod.WithRelayBatchSize(0)}, wantErr: sqlmod.ErrInvalidRelay},
+		{name: "Backoff", pub: &recordPublisher{}, opts: []sqlmod.RelayOpt{sqlmod.WithRelayBackoff(time.Second, time.Millisecond)}, wantErr: sqlmod.ErrInvalidRelay},
+		{name: "Lease", pub: &recordPublisher{}, opts: []sqlmod.RelayOpt{sqlmod.WithRelayLease(0)}, wantErr: sqlmod.ErrInvalidRelay},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := sqlmod.NewRelay(nil, sqlmod.NewOutbox(), tt.pub, tt.opts...)
+			require.ErrorIs(t, relay.Init(), tt.wantErr)
+		})
+	}
+}
+
+func pending(t *testing.T, dbx *sqlmod.DB) int {
+	n := 0
+	require.NoError(t, dbx.DB().QueryRow(`SELECT count(*) FROM events WHERE delivered_at IS NULL`).Scan(&n))
+	return n
+}
+
+type recordPublisher struct {
+	mu   sync.Mutex
+	msgs []sqlmod.OutboxMessage
+}
+
+func (p *recordPublisher) Publish(_ context.Context, msg sqlmod.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *recordPublisher) get() []sqlmod.OutboxMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.msgs
+}
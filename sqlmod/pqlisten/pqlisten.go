@@ -0,0 +1,117 @@
+// Package pqlisten implements sqlmod.ListenBackend with Postgres LISTEN
+// using github.com/lib/pq.
+package pqlisten
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/lib/pq"
+)
+
+const ErrInvalidPingInterval = errStr("ping interval must be positive")
+
+type errStr string
+
+func (e errStr) Error() string { return string(e) }
+
+// Backend listens on a dedicated connection opened with the DSN.
+type Backend struct {
+	dsn  string
+	ping time.Duration
+	err  error
+}
+
+// New creates Backend connecting with dsn. The connection is pinged every
+// 30 seconds to notice when it is lost. If an option fails, Listen returns
+// its error.
+func New(dsn string, opts ...Opt) *Backend {
+	b := &Backend{dsn: dsn, ping: 30 * time.Second}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			b.err = fmt.Errorf("failed to apply option: %w", err)
+			break
+		}
+	}
+	return b
+}
+
+func (b *Backend) Listen(ctx context.Context, channels []string, fn func(sqlmod.Notification)) error {
+	if b.err != nil {
+		return b.err
+	}
+	// pq.Listener reconnects on its own and drops notifications meanwhile
+	// without failing, while sqlmod.Listener already reconnects with backoff.
+	// The low-level connection makes failures reach sqlmod.Listener instead.
+	notifications := make(chan *pq.Notification, 32)
+	conn, err := pq.NewListenerConn(b.dsn, notifications)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+		// The connection stops once nobody waits for it to deliver.
+		for range notifications {
+		}
+	}()
+
+	// LISTEN waits for replies read by the goroutine delivering
+	// notifications, so notifications are read meanwhile.
+	listened := make(chan error, 1)
+	go func() {
+		for _, channel := range channels {
+			if _, err := conn.Listen(channel); err != nil {
+				listened <- err
+				return
+			}
+		}
+		listened <- nil
+	}()
+
+	// Ping waits for its reply the same way, so it runs in the background
+	// and only one ping is in flight at a time.
+	pinged := make(chan error, 1)
+	pinging := false
+	t := time.NewTicker(b.ping)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-listened:
+			if err != nil {
+				return err
+			}
+		case n, ok := <-notifications:
+			if !ok {
+				return conn.Err()
+			}
+			fn(sqlmod.Notification{Channel: n.Channel, Payload: n.Extra})
+		case <-t.C:
+			if !pinging {
+				pinging = true
+				go func() { pinged <- conn.Ping() }()
+			}
+		case err := <-pinged:
+			if err != nil {
+				return err
+			}
+			pinging = false
+		}
+	}
+}
+
+type Opt func(*Backend) error
+
+// WithPingInterval sets how often the connection is pinged.
+func WithPingInterval(d time.Duration) Opt {
+	return func(b *Backend) error {
+		if d <= 0 {
+			return ErrInvalidPingInterval
+		}
+		b.ping = d
+		return nil
+	}
+}
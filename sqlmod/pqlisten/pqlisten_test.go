@@ -0,0 +1,22 @@
+package pqlisten_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/go-srvc/mods/sqlmod/pqlisten"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_ConnectError(t *testing.T) {
+	b := pqlisten.New("postgres://localhost:1/db?sslmode=disable&connect_timeout=1")
+	err := b.Listen(context.Background(), []string{"users"}, func(sqlmod.Notification) {})
+	require.ErrorContains(t, err, "connect")
+}
+
+func TestBackend_InvalidPingInterval(t *testing.T) {
+	b := pqlisten.New("postgres://localhost:1/db?sslmode=disable", pqlisten.WithPingInterval(0))
+	err := b.Listen(context.Background(), []string{"users"}, func(sqlmod.Notification) {})
+	require.ErrorIs(t, err, pqlisten.ErrInvalidPingInterval)
+}
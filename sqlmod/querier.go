@@ -0,0 +1,114 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const ErrInvalidQueryTimeout = errStr("query timeout must be positive")
+
+// Querier runs queries on the database with a timeout for each statement
+// whose context has no deadline. Queries in flight are canceled as soon as
+// Stop is called, without waiting for the timeout set by
+// WithShutdownTimeout.
+type Querier struct {
+	d       *DB
+	timeout time.Duration
+}
+
+// Querier returns Querier using the timeout set by WithQueryTimeout, 30s by
+// default. Only valid after Init has run.
+func (d *DB) Querier() *Querier {
+	return &Querier{d: d, timeout: d.queryTimeout}
+}
+
+// WithTimeout returns a copy of q using timeout instead, or no timeout if
+// it is zero, for example for a single slow report.
+func (q *Querier) WithTimeout(timeout time.Duration) *Querier {
+	return &Querier{d: q.d, timeout: timeout}
+}
+
+// context returns ctx with the timeout unless it already has a deadline,
+// canceled also when Stop is called.
+func (q *Querier) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if _, ok := ctx.Deadline(); ok || q.timeout <= 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, q.timeout)
+	}
+	stop := context.AfterFunc(q.d.queries, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (q *Querier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := q.context(ctx)
+	defer cancel()
+	return q.d.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query. The timeout covers reading the rows, which
+// must be closed.
+func (q *Querier) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, cancel := q.context(ctx)
+	rows, err := q.d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// QueryRowContext runs a query expected to return at most one row. The
+// timeout covers Row.Scan, which must be called as with sql.Row.
+func (q *Querier) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	ctx, cancel := q.context(ctx)
+	return &Row{row: q.d.db.QueryRowContext(ctx, query, args...), cancel: cancel}
+}
+
+// Rows is sql.Rows releasing the timeout of Querier when closed.
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+func (r *Rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// Row is sql.Row releasing the timeout of Querier once scanned, or once Err
+// reports that the query failed.
+type Row struct {
+	row    *sql.Row
+	cancel context.CancelFunc
+}
+
+func (r *Row) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
+
+func (r *Row) Err() error {
+	err := r.row.Err()
+	if err != nil {
+		r.cancel()
+	}
+	return err
+}
+
+// WithQueryTimeout sets the timeout of statements run through Querier
+// without a deadline. Default is 30s.
+func WithQueryTimeout(timeout time.Duration) Opt {
+	return func(d *DB) error {
+		if timeout <= 0 {
+			return ErrInvalidQueryTimeout
+		}
+		d.queryTimeout = timeout
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package sqlmod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerier_Timeout(t *testing.T) {
+	c := newBlockingConnector()
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithQueryTimeout(10*time.Millisecond))
+	require.NoError(t, dbx.Init())
+	go func() {
+		for range c.started {
+		}
+	}()
+
+	_, err := dbx.Querier().ExecContext(context.Background(), "block")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dbx.Querier().ExecContext(ctx, "block")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "deadline of the caller is kept")
+
+	_, err = dbx.Querier().ExecContext(context.Background(), "fast")
+	require.NoError(t, err)
+	require.NoError(t, dbx.Stop())
+	close(c.started)
+}
+
+func TestQuerier_Stop(t *testing.T) {
+	c := newBlockingConnector()
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithShutdownTimeout(time.Minute))
+	require.NoError(t, dbx.Init())
+
+	execErr := make(chan error)
+	go func() {
+		_, err := dbx.Querier().WithTimeout(0).ExecContext(context.Background(), "block")
+		execErr <- err
+	}()
+	<-c.started
+	start := time.Now()
+	require.NoError(t, dbx.Stop())
+	require.Less(t, time.Since(start), time.Minute)
+	require.ErrorIs(t, <-execErr, context.Canceled)
+}
+
+func TestQuerier_Rows(t *testing.T) {
+	dbx := newCounterDB(t, sqlmod.WithQueryTimeout(time.Second))
+	q := dbx.Querier()
+	ctx := context.Background()
+
+	rows, err := q.QueryContext(ctx, `SELECT n FROM counter UNION SELECT 1`)
+	require.NoError(t, err)
+	var got []int
+	for rows.Next() {
+		n := 0
+		require.NoError(t, rows.Scan(&n))
+		got = append(got, n)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	require.Equal(t, []int{0, 1}, got)
+
+	n := -1
+	require.NoError(t, q.QueryRowContext(ctx, `SELECT n FROM counter`).Scan(&n))
+	require.Equal(t, 0, n)
+	row := q.QueryRowContext(ctx, `SELECT missing FROM counter`)
+	require.Error(t, row.Err())
+	require.Error(t, row.Scan(&n))
+}
+
+func TestQuerier_Errors(t *testing.T) {
+	dbx := sqlmod.New(sqlmod.WithDB(nil), sqlmod.WithQueryTimeout(0))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidQueryTimeout)
+}
@@ -0,0 +1,150 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const RegistryID = "sqlmod-registry"
+
+const ErrDuplicateID = errStr("duplicate db id")
+
+// Registry is a module managing several databases told apart by the IDs
+// set with WithID. Databases are initialized in order and stopped in
+// reverse, so add the ones others depend on first. The connections of
+// every database are reported as metrics.
+type Registry struct {
+	dbs  []*DB
+	byID map[string]*DB
+	reg  metric.Registration
+}
+
+// NewRegistry creates Registry managing dbs.
+func NewRegistry(dbs ...*DB) *Registry {
+	return &Registry{dbs: dbs}
+}
+
+func (r *Registry) Init() error {
+	r.byID = map[string]*DB{}
+	for i, db := range r.dbs {
+		if err := db.Init(); err != nil {
+			return errors.Join(fmt.Errorf("%s: %w", db.ID(), err), r.stop(r.dbs[:i]))
+		}
+		if _, ok := r.byID[db.ID()]; ok {
+			return errors.Join(fmt.Errorf("%w: %s", ErrDuplicateID, db.ID()), r.stop(r.dbs[:i+1]))
+		}
+		r.byID[db.ID()] = db
+	}
+	reg, err := r.registerMetrics()
+	if err != nil {
+		return errors.Join(err, r.stop(r.dbs))
+	}
+	r.reg = reg
+	return nil
+}
+
+// Run runs every database until Stop is called or one of them fails.
+func (r *Registry) Run() error {
+	errs := make(chan error, len(r.dbs))
+	for _, db := range r.dbs {
+		go func() {
+			if err := db.Run(); err != nil {
+				errs <- fmt.Errorf("%s: %w", db.ID(), err)
+				return
+			}
+			errs <- nil
+		}()
+	}
+	for range r.dbs {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops the databases in reverse order.
+func (r *Registry) Stop() error {
+	var err error
+	if r.reg != nil {
+		err = r.reg.Unregister()
+	}
+	return errors.Join(err, r.stop(r.dbs))
+}
+
+func (r *Registry) ID() string { return RegistryID }
+
+// DB returns the database with given ID, or nil if there is none. Only
+// valid after Init has run.
+func (r *Registry) DB(id string) *DB { return r.byID[id] }
+
+// Check runs Check of every database concurrently and returns their
+// errors prefixed with the ID of the database.
+func (r *Registry) Check(ctx context.Context) error {
+	errs := make([]error, len(r.dbs))
+	wg := sync.WaitGroup{}
+	for i, db := range r.dbs {
+		wg.Go(func() {
+			if err := db.Check(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", db.ID(), err)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stats returns the pool statistics of every database by ID.
+func (r *Registry) Stats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats, len(r.dbs))
+	for _, db := range r.dbs {
+		stats[db.ID()] = db.DB().Stats()
+	}
+	return stats
+}
+
+// stop stops dbs in reverse order.
+func (r *Registry) stop(dbs []*DB) error {
+	var errs []error
+	for i := len(dbs) - 1; i >= 0; i-- {
+		if err := dbs[i].Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dbs[i].ID(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registerMetrics reports the connections of every database with the
+// sqlmod.id attribute when metrics are collected. Waits for connections
+// are recorded by each database with WithPoolMetrics.
+func (r *Registry) registerMetrics() (metric.Registration, error) {
+	m := otel.Meter(instrumentationName)
+	conns, err := m.Int64ObservableGauge("sqlmod.pool.connections",
+		metric.WithDescription("Number of open connections by state."),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	maxConns, err := m.Int64ObservableGauge("sqlmod.pool.max_connections",
+		metric.WithDescription("Maximum number of open connections, 0 for unlimited."),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	return m.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for id, s := range r.Stats() {
+			db := attribute.String("sqlmod.id", id)
+			o.ObserveInt64(conns, int64(s.InUse), metric.WithAttributes(db, attribute.String("state", "in_use")))
+			o.ObserveInt64(conns, int64(s.Idle), metric.WithAttributes(db, attribute.String("state", "idle")))
+			o.ObserveInt64(maxConns, int64(s.MaxOpenConnections), metric.WithAttributes(db))
+		}
+		return nil
+	}, conns, maxConns)
+}
@@ -0,0 +1,92 @@
+package sqlmod_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegistry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	dir := t.TempDir()
+	users := sqlmod.New(sqlmod.WithID("users"), sqlmod.WithDSN("sqlite3", filepath.Join(dir, "users.db")))
+	orders := sqlmod.New(sqlmod.WithID("orders"), sqlmod.WithDSN("sqlite3", filepath.Join(dir, "orders.db")), sqlmod.WithMaxOpenConns(3))
+	r := sqlmod.NewRegistry(users, orders)
+	require.NoError(t, r.Init())
+	require.Equal(t, sqlmod.RegistryID, r.ID())
+	require.Equal(t, users, r.DB("users"))
+	require.Equal(t, orders, r.DB("orders"))
+	require.Nil(t, r.DB("missing"))
+	wg := &errgroup.ErrGroup{}
+	wg.Go(r.Run)
+
+	require.NoError(t, r.Check(context.Background()))
+	require.NoError(t, orders.DB().Ping())
+	stats := r.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, 3, stats["orders"].MaxOpenConnections)
+
+	gauge := collect(t, reader)["sqlmod.pool.max_connections"].(metricdata.Gauge[int64])
+	maxConns := map[string]int64{}
+	for _, dp := range gauge.DataPoints {
+		id, _ := dp.Attributes.Value(attribute.Key("sqlmod.id"))
+		maxConns[id.AsString()] = dp.Value
+	}
+	require.Equal(t, map[string]int64{"users": 0, "orders": 3}, maxConns)
+
+	require.NoError(t, r.Stop())
+	require.NoError(t, wg.Wait())
+	require.ErrorContains(t, users.DB().Ping(), "database is closed")
+	require.ErrorContains(t, orders.DB().Ping(), "database is closed")
+}
+
+func TestRegistry_Check(t *testing.T) {
+	c := &pingConnector{}
+	r := sqlmod.NewRegistry(
+		sqlmod.New(sqlmod.WithID("users"), sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "users.db"))),
+		sqlmod.New(sqlmod.WithID("orders"), sqlmod.WithConnector(c)),
+	)
+	require.NoError(t, r.Init())
+	c.fail.Store(true)
+	err := r.Check(context.Background())
+	require.ErrorIs(t, err, sqlmod.ErrUnhealthy)
+	require.ErrorContains(t, err, "orders: db unhealthy")
+	require.NotContains(t, err.Error(), "users")
+	require.NoError(t, r.Stop())
+}
+
+func TestRegistry_InitError(t *testing.T) {
+	first := sqlmod.New(sqlmod.WithID("first"), sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "first.db")))
+	r := sqlmod.NewRegistry(first, sqlmod.New(sqlmod.WithID("second")))
+	err := r.Init()
+	require.ErrorIs(t, err, sqlmod.ErrDBNotSet)
+	require.ErrorContains(t, err, "second: db not set")
+	require.ErrorContains(t, first.DB().Ping(), "database is closed", "initialized databases are stopped")
+
+	r = sqlmod.NewRegistry(
+		sqlmod.New(sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "a.db"))),
+		sqlmod.New(sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "b.db"))),
+	)
+	require.ErrorIs(t, r.Init(), sqlmod.ErrDuplicateID)
+	require.NoError(t, sqlmod.NewRegistry().Stop(), "Stop without Init")
+}
+
+func TestWithID(t *testing.T) {
+	dbx := sqlmod.New(sqlmod.WithID("users"), sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db")))
+	require.NoError(t, dbx.Init())
+	require.Equal(t, "users", dbx.ID())
+	require.NoError(t, dbx.Stop())
+
+	dbx = sqlmod.New(sqlmod.WithID(""))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidID)
+}
@@ -0,0 +1,236 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const RelayID = "outbox-relay"
+
+const (
+	ErrPublisherNotSet = errStr("publisher not set")
+	ErrInvalidRelay    = errStr("relay interval, batch size, backoff and lease must be positive")
+)
+
+// Publisher publishes messages from the outbox, for example to a message
+// broker. Messages are published at least once, so consumers should
+// ignore messages whose ID they have already seen.
+type Publisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, msg OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg OutboxMessage) error { return f(ctx, msg) }
+
+// Relay is a module publishing messages enqueued to an Outbox. It polls
+// the outbox table, publishes pending messages oldest first and marks
+// them delivered. Failed messages are retried with exponential backoff.
+// Place it after the database in srvc.Run.
+//
+// Several replicas can run Relay on the same table. Each message is claimed
+// before it's published and skipped by the other relays until the lease set
+// by WithRelayLease expires, which also bounds the time to publish it.
+type Relay struct {
+	db     *DB
+	outbox *Outbox
+	pub    Publisher
+	opts   []RelayOpt
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	interval   time.Duration
+	batch      int
+	backoffMin time.Duration
+	backoffMax time.Duration
+	lease      time.Duration
+	log        *slog.Logger
+}
+
+// NewRelay creates Relay publishing messages of outbox stored in db
+// through pub.
+func NewRelay(db *DB, outbox *Outbox, pub Publisher, opts ...RelayOpt) *Relay {
+	return &Relay{db: db, outbox: outbox, pub: pub, opts: opts}
+}
+
+func (r *Relay) Init() error {
+	r.done = make(chan struct{})
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.interval, r.batch = time.Second, 100
+	r.backoffMin, r.backoffMax = time.Second, 5*time.Minute
+	r.lease = time.Minute
+	r.log = slog.Default()
+	for _, opt := range r.opts {
+		if err := opt(r); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	if r.pub == nil {
+		return ErrPublisherNotSet
+	}
+	return nil
+}
+
+// Run publishes pending messages every interval until Stop is called. A
+// full batch is followed by the next one right away.
+func (r *Relay) Run() error {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		n, err := r.relay(r.ctx)
+		if err != nil && r.ctx.Err() == nil {
+			r.log.Error("Failed to relay outbox", slog.Any("error", err))
+		}
+		if n == r.batch && err == nil {
+			continue
+		}
+		select {
+		case <-r.done:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Stop cancels publishing in progress and stops Run.
+func (r *Relay) Stop() error {
+	r.cancel()
+	close(r.done)
+	return nil
+}
+
+func (r *Relay) ID() string { return RelayID }
+
+// relay publishes one batch of pending messages and returns its size.
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	db := r.db.DB()
+	msgs, err := r.outbox.pending(ctx, db, r.batch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	var errs []error
+	for _, msg := range msgs {
+		if err := r.publish(ctx, db, msg); err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return len(msgs), errors.Join(errs...)
+}
+
+// publish claims msg and publishes it, unless another relay claimed it.
+func (r *Relay) publish(ctx context.Context, db *sql.DB, msg pendingMessage) error {
+	claimed := outboxClock.next() + r.lease.Nanoseconds()
+	ok, err := r.outbox.claim(ctx, db, msg, claimed)
+	if err != nil {
+		return fmt.Errorf("failed to claim %s: %w", msg.ID, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, r.lease)
+	err = r.pub.Publish(pubCtx, msg.OutboxMessage)
+	cancel()
+	switch {
+	case err == nil:
+		// A failure here publishes the message again once the claim expires.
+		if err := r.outbox.delivered(context.WithoutCancel(ctx), db, msg.ID); err != nil {
+			return fmt.Errorf("failed to mark %s delivered: %w", msg.ID, err)
+		}
+	case ctx.Err() != nil:
+		// Stopped, so the message is due again right away.
+		if err := r.outbox.release(context.WithoutCancel(ctx), db, msg.ID, claimed, msg.due); err != nil {
+			return fmt.Errorf("failed to release %s: %w", msg.ID, err)
+		}
+	default:
+		delay := r.backoff(msg.Attempts)
+		r.log.Warn("Failed to publish outbox message",
+			slog.String("id", msg.ID),
+			slog.String("topic", msg.Topic),
+			slog.Int("attempts", msg.Attempts+1),
+			slog.Any("error", err),
+		)
+		if err := r.outbox.failed(ctx, db, msg.ID, claimed, time.Now().Add(delay), err); err != nil {
+			return fmt.Errorf("failed to record failure of %s: %w", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+// backoff returns the delay before retrying a message that has failed
+// attempts times before this failure.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.backoffMin
+	for range attempts {
+		if delay >= r.backoffMax/2 {
+			return r.backoffMax
+		}
+		delay *= 2
+	}
+	return delay
+}
+
+type RelayOpt func(*Relay) error
+
+// WithRelayInterval sets how often the outbox is polled. Default is 1s.
+func WithRelayInterval(interval time.Duration) RelayOpt {
+	return func(r *Relay) error {
+		if interval <= 0 {
+			return ErrInvalidRelay
+		}
+		r.interval = interval
+		return nil
+	}
+}
+
+// WithRelayBatchSize sets how many messages are read per poll. Default is 100.
+func WithRelayBatchSize(n int) RelayOpt {
+	return func(r *Relay) error {
+		if n <= 0 {
+			return ErrInvalidRelay
+		}
+		r.batch = n
+		return nil
+	}
+}
+
+// WithRelayBackoff sets the delay before retrying a failed message,
+// doubled after each failure up to maxDelay. Default is 1s growing up to 5m.
+func WithRelayBackoff(initial, maxDelay time.Duration) RelayOpt {
+	return func(r *Relay) error {
+		if initial <= 0 || maxDelay < initial {
+			return ErrInvalidRelay
+		}
+		r.backoffMin, r.backoffMax = initial, maxDelay
+		return nil
+	}
+}
+
+// WithRelayLease sets how long a message claimed by the relay is skipped
+// by other relays, and how long publishing it may take. Default is 1m.
+func WithRelayLease(lease time.Duration) RelayOpt {
+	return func(r *Relay) error {
+		if lease <= 0 {
+			return ErrInvalidRelay
+		}
+		r.lease = lease
+		return nil
+	}
+}
+
+// WithRelayLogger sets logger for publish failures. Default is slog.Default().
+func WithRelayLogger(l *slog.Logger) RelayOpt {
+	return func(r *Relay) error {
+		r.log = l
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ErrInvalidHealthCheck = errStr("health check interval and timeout must be positive")
+	ErrInvalidReplica     = errStr("recycling, pool metrics and check interval are not supported for replicas")
+)
+
+type replica struct {
+	db      *DB
+	healthy atomic.Bool
+}
+
+// Reader returns a healthy replica for read-only queries, taking turns
+// between replicas, or the primary if no replica is healthy or none is set.
+// Only valid after Init has run.
+func (d *DB) Reader() *sql.DB {
+	n := len(d.replicas)
+	start := int(d.nextReplica.Add(1) % uint64(max(n, 1)))
+	for i := range n {
+		if r := d.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db.db
+		}
+	}
+	return d.db
+}
+
+// initReplicas initializes replicas in order, stopping the ones already
+// initialized if one fails. Replicas without their own shutdown timeout or
+// query statistics use the ones of the primary, see Init.
+func (d *DB) initReplicas() error {
+	for i, r := range d.replicas {
+		if err := r.db.Init(); err != nil {
+			for _, r := range d.replicas[:i] {
+				_ = r.db.Stop()
+			}
+			return err
+		}
+		r.healthy.Store(true)
+	}
+	return nil
+}
+
+// checkReplicas pings every replica and marks the ones not responding
+// within the health check timeout as unhealthy until they respond again.
+func (d *DB) checkReplicas() {
+	wg := sync.WaitGroup{}
+	for _, r := range d.replicas {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), d.healthTimeout)
+			defer cancel()
+			r.healthy.Store(r.db.db.PingContext(ctx) == nil)
+		})
+	}
+	wg.Wait()
+}
+
+// WithReplica adds a read replica configured with given options, for
+// example WithDSN and pool options. Replicas are initialized after the
+// primary and closed in Stop. See DB.Reader.
+//
+// Replicas are not run, so Init fails with ErrInvalidReplica if they are
+// given WithRecycleOnChange, WithPoolMetrics or WithCheckInterval.
+func WithReplica(opts ...Opt) Opt {
+	return func(d *DB) error {
+		r := New(opts...)
+		r.primary = d
+		d.replicas = append(d.replicas, &replica{db: r})
+		return nil
+	}
+}
+
+// WithReplicaHealthCheck sets how often replicas are pinged while the
+// module runs and how long a ping may take before the replica is skipped.
+// Default is every 5s with a 1s timeout.
+func WithReplicaHealthCheck(interval, timeout time.Duration) Opt {
+	return func(d *DB) error {
+		if interval <= 0 || timeout <= 0 {
+			return ErrInvalidHealthCheck
+		}
+		d.healthInterval, d.healthTimeout = interval, timeout
+		return nil
+	}
+}
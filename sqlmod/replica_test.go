@@ -0,0 +1,115 @@
+package sqlmod_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicas(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, openNamed(t, filepath.Join(dir, "replica1.db"), "replica1").Close())
+	replica2 := openNamed(t, filepath.Join(dir, "replica2.db"), "replica2")
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("sqlite3", filepath.Join(dir, "primary.db")),
+		sqlmod.WithReplica(
+			sqlmod.WithDSN("sqlite3", filepath.Join(dir, "replica1.db")),
+			sqlmod.WithMaxOpenConns(2),
+		),
+		sqlmod.WithReplica(sqlmod.WithDB(replica2)),
+		sqlmod.WithReplicaHealthCheck(time.Millisecond, time.Second),
+	)
+	require.NoError(t, dbx.Init())
+	setName(t, dbx.DB(), "primary")
+	replica1 := dbx.Reader()
+	if replica1 == replica2 {
+		replica1 = dbx.Reader()
+	}
+	require.Equal(t, 2, replica1.Stats().MaxOpenConnections)
+
+	require.ElementsMatch(t, []string{"replica1", "replica2"}, []string{name(t, dbx.Reader()), name(t, dbx.Reader())})
+	var got string
+	require.NoError(t, dbx.WithTx(context.Background(), &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT name FROM whoami`).Scan(&got)
+	}))
+	require.Contains(t, []string{"replica1", "replica2"}, got)
+	require.NoError(t, dbx.WithTx(context.Background(), nil, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT name FROM whoami`).Scan(&got)
+	}))
+	require.Equal(t, "primary", got)
+
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+
+	// A failing replica is skipped after the next health check.
+	require.NoError(t, replica2.Close())
+	require.Eventually(t, func() bool {
+		return dbx.Reader() == replica1 && dbx.Reader() == replica1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, replica1.Close())
+	require.Eventually(t, func() bool { return dbx.Reader() == dbx.DB() }, time.Second, time.Millisecond)
+
+	require.NoError(t, dbx.Stop())
+	require.NoError(t, wg.Wait())
+	require.ErrorContains(t, dbx.DB().Ping(), "closed")
+}
+
+func TestReplicas_InitFailed(t *testing.T) {
+	dir := t.TempDir()
+	replica1 := openNamed(t, filepath.Join(dir, "replica1.db"), "replica1")
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("sqlite3", filepath.Join(dir, "primary.db")),
+		sqlmod.WithReplica(sqlmod.WithDB(replica1)),
+		sqlmod.WithReplica(),
+	)
+	err := dbx.Init()
+	require.ErrorIs(t, err, sqlmod.ErrDBNotSet)
+	require.ErrorContains(t, err, "replica")
+	require.ErrorContains(t, replica1.Ping(), "closed")
+	require.ErrorContains(t, dbx.DB().Ping(), "closed")
+}
+
+func TestReplicas_InvalidHealthCheck(t *testing.T) {
+	dbx := sqlmod.New(sqlmod.WithDB(&sql.DB{}), sqlmod.WithReplicaHealthCheck(0, time.Second))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidHealthCheck)
+}
+
+func TestReplicas_InvalidOpts(t *testing.T) {
+	dsn := func(context.Context) (string, error) { return ":memory:", nil }
+	for _, opts := range [][]sqlmod.Opt{
+		{sqlmod.WithDSNFunc("sqlite3", dsn), sqlmod.WithRecycleOnChange(time.Second)},
+		{sqlmod.WithDSN("sqlite3", ":memory:"), sqlmod.WithPoolMetrics(time.Second)},
+		{sqlmod.WithDSN("sqlite3", ":memory:"), sqlmod.WithCheckInterval(time.Second)},
+	} {
+		dbx := sqlmod.New(sqlmod.WithDSN("sqlite3", ":memory:"), sqlmod.WithReplica(opts...))
+		err := dbx.Init()
+		require.ErrorIs(t, err, sqlmod.ErrInvalidReplica)
+		require.ErrorContains(t, dbx.DB().Ping(), "closed")
+	}
+}
+
+func openNamed(t *testing.T, dsn, name string) *sql.DB {
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	setName(t, db, name)
+	return db
+}
+
+func setName(t *testing.T, db *sql.DB, name string) {
+	_, err := db.Exec(`CREATE TABLE whoami (name TEXT); INSERT INTO whoami VALUES (?)`, name)
+	require.NoError(t, err)
+}
+
+func name(t *testing.T, db *sql.DB) string {
+	var name string
+	require.NoError(t, db.QueryRow(`SELECT name FROM whoami`).Scan(&name))
+	return name
+}
@@ -0,0 +1,102 @@
+package sqlmod
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	ErrDBStopped      = errStr("db stopped")
+	ErrQueriesAborted = errStr("queries aborted at shutdown")
+)
+
+// tracker counts queries and transactions in flight so that Stop can wait
+// for them and cancel the ones left when the shutdown timeout passes.
+type tracker struct {
+	mu       sync.Mutex
+	stopping bool
+	active   int
+	idle     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func newTracker() *tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tracker{idle: make(chan struct{}), ctx: ctx, cancel: cancel}
+}
+
+// start registers a query or transaction and returns a context canceled
+// when the shutdown timeout passes. Once stopping, only queries of
+// transactions already running are accepted. done must be called when the
+// work completes.
+func (t *tracker) start(ctx context.Context, inTx bool) (context.Context, func(), error) {
+	t.mu.Lock()
+	if t.stopping && !inTx {
+		t.mu.Unlock()
+		return nil, nil, ErrDBStopped
+	}
+	t.active++
+	t.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.active--
+		if t.stopping && t.active == 0 {
+			close(t.idle)
+		}
+	}, nil
+}
+
+// stop refuses new work and waits up to timeout for the work in flight,
+// then cancels the rest and returns how much was canceled.
+func (t *tracker) stop(timeout time.Duration) int {
+	t.mu.Lock()
+	if t.stopping {
+		t.mu.Unlock()
+		return 0
+	}
+	t.stopping = true
+	if t.active == 0 {
+		close(t.idle)
+	}
+	t.mu.Unlock()
+	if timeout <= 0 {
+		return 0
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-t.idle:
+		return 0
+	case <-timer.C:
+	}
+	t.mu.Lock()
+	aborted := t.active
+	t.mu.Unlock()
+	t.cancel()
+	return aborted
+}
+
+// WithShutdownTimeout makes Stop wait up to d for queries and transactions
+// in flight before closing the database. New queries fail with ErrDBStopped
+// once Stop has been called, except for queries of transactions already
+// running. Queries still running when d has passed are canceled and Stop
+// returns ErrQueriesAborted with their count. Replicas without their own
+// timeout use the same one. Default is to close right away.
+//
+// Only queries of databases opened by the module are tracked, so the
+// option has no effect with WithDB and WithDBFn.
+func WithShutdownTimeout(d time.Duration) Opt {
+	return func(db *DB) error {
+		db.shutdownTimeout = d
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package sqlmod_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_Waits(t *testing.T) {
+	c := newBlockingConnector()
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithShutdownTimeout(time.Minute))
+	require.NoError(t, dbx.Init())
+
+	execErr := make(chan error)
+	go func() {
+		_, err := dbx.DB().Exec("block")
+		execErr <- err
+	}()
+	<-c.started
+	tx, err := dbx.DB().Begin()
+	require.NoError(t, err)
+
+	stopErr := make(chan error)
+	go func() { stopErr <- dbx.Stop() }()
+	require.Eventually(t, func() bool {
+		_, err := dbx.DB().Exec("fast")
+		return errors.Is(err, sqlmod.ErrDBStopped)
+	}, time.Second, time.Millisecond)
+
+	_, err = tx.Exec("fast")
+	require.NoError(t, err, "running transactions can finish")
+	require.NoError(t, tx.Commit())
+	close(c.release)
+	require.NoError(t, <-execErr)
+	require.NoError(t, <-stopErr)
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	c := newBlockingConnector()
+	dbx := sqlmod.New(sqlmod.WithConnector(c), sqlmod.WithShutdownTimeout(10*time.Millisecond))
+	require.NoError(t, dbx.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+
+	execErr := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := dbx.DB().Exec("block")
+			execErr <- err
+		}()
+		<-c.started
+	}
+
+	err := dbx.Stop()
+	require.ErrorIs(t, err, sqlmod.ErrQueriesAborted)
+	require.ErrorContains(t, err, "2 in flight")
+	require.ErrorIs(t, <-execErr, context.Canceled)
+	require.ErrorIs(t, <-execErr, context.Canceled)
+	require.NoError(t, wg.Wait())
+}
+
+func TestShutdown_Rows(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		drain   bool
+		wantErr error
+	}{
+		{name: "Open", wantErr: sqlmod.ErrQueriesAborted},
+		{name: "Drained", drain: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dbx := sqlmod.New(
+				sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db")),
+				sqlmod.WithShutdownTimeout(10*time.Millisecond),
+			)
+			require.NoError(t, dbx.Init())
+			rows, err := dbx.DB().Query(`SELECT 1 UNION SELECT 2`)
+			require.NoError(t, err)
+			if tt.drain {
+				for rows.Next() {
+				}
+				require.NoError(t, rows.Err())
+			}
+			require.ErrorIs(t, dbx.Stop(), tt.wantErr)
+			require.NoError(t, rows.Close())
+		})
+	}
+}
+
+type blockingConnector struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingConnector() *blockingConnector {
+	return &blockingConnector{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (c *blockingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &blockingConn{c: c}, nil
+}
+
+func (c *blockingConnector) Driver() driver.Driver { return nil }
+
+// blockingConn blocks on the query "block" until released or canceled and
+// runs other queries right away.
+type blockingConn struct {
+	fakeConn
+	c *blockingConnector
+}
+
+func (c *blockingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *blockingConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if query != "block" {
+		return driver.RowsAffected(0), nil
+	}
+	c.c.started <- struct{}{}
+	select {
+	case <-c.c.release:
+		return driver.RowsAffected(1), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
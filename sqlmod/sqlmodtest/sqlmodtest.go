@@ -0,0 +1,95 @@
+// Package sqlmodtest creates an isolated database for each test. It
+// doesn't import any driver, so tests import the one they use.
+package sqlmodtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-srvc/mods/sqlmod"
+)
+
+// Factory creates an empty database for a test and returns the option
+// opening it. Databases that need removing are removed with t.Cleanup.
+type Factory func(t testing.TB) sqlmod.Opt
+
+// New creates a database with f and returns a sqlmod.DB initialized with
+// it and given options, for example sqlmod.WithMigrations to create the
+// schema. The DB is stopped with t.Cleanup. Every call gets a database of
+// its own, so tests using it can run in parallel.
+func New(t testing.TB, f Factory, opts ...sqlmod.Opt) *sqlmod.DB {
+	t.Helper()
+	dbx := sqlmod.New(append([]sqlmod.Opt{f(t)}, opts...)...)
+	if err := dbx.Init(); err != nil {
+		t.Fatalf("sqlmodtest: failed to init db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := dbx.Stop(); err != nil {
+			t.Errorf("sqlmodtest: failed to stop db: %v", err)
+		}
+	})
+	return dbx
+}
+
+// SQLite creates a database file in t.TempDir for the SQLite driver
+// registered as driverName, for example "sqlite3" or "sqlite".
+func SQLite(driverName string) Factory {
+	return func(t testing.TB) sqlmod.Opt {
+		return sqlmod.WithDSN(driverName, filepath.Join(t.TempDir(), "test.db"))
+	}
+}
+
+// SQLiteMemory creates an in-memory database shared by the connections of
+// the pool. It is removed once the last connection is closed, so the pool
+// must keep at least one idle connection.
+func SQLiteMemory(driverName string) Factory {
+	return func(testing.TB) sqlmod.Opt {
+		return sqlmod.WithDSN(driverName, fmt.Sprintf("file:%s?mode=memory&cache=shared", name()))
+	}
+}
+
+// Postgres creates a database through adminDSN, cloned from template when
+// it isn't empty, which is much faster than running every migration for
+// every test. dsn returns the DSN for connecting to the created database.
+// The database is dropped when the test ends.
+func Postgres(driverName, adminDSN, template string, dsn func(dbName string) string) Factory {
+	return func(t testing.TB) sqlmod.Opt {
+		t.Helper()
+		admin, err := sql.Open(driverName, adminDSN)
+		if err != nil {
+			t.Fatalf("sqlmodtest: failed to open admin db: %v", err)
+		}
+		t.Cleanup(func() { _ = admin.Close() })
+
+		dbName := name()
+		query := "CREATE DATABASE " + quote(dbName)
+		if template != "" {
+			query += " TEMPLATE " + quote(template)
+		}
+		if _, err := admin.ExecContext(context.Background(), query); err != nil {
+			t.Fatalf("sqlmodtest: failed to create db: %v", err)
+		}
+		// Cleanups run in reverse, so the DB is stopped before the drop.
+		t.Cleanup(func() {
+			if _, err := admin.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+quote(dbName)); err != nil {
+				t.Errorf("sqlmodtest: failed to drop db: %v", err)
+			}
+		})
+		return sqlmod.WithDSN(driverName, dsn(dbName))
+	}
+}
+
+// name returns a random database name, unique even across processes
+// sharing a database server.
+func name() string {
+	return "test_" + strings.ToLower(rand.Text())
+}
+
+func quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
@@ -0,0 +1,115 @@
+package sqlmodtest_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/go-srvc/mods/sqlmod/sqlmodtest"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var migrations = fstest.MapFS{
+	"1_create_items.up.sql":   {Data: []byte(`CREATE TABLE items (name TEXT NOT NULL)`)},
+	"1_create_items.down.sql": {Data: []byte(`DROP TABLE items`)},
+}
+
+func TestNew(t *testing.T) {
+	for name, f := range map[string]sqlmodtest.Factory{
+		"SQLite":       sqlmodtest.SQLite("sqlite3"),
+		"SQLiteMemory": sqlmodtest.SQLiteMemory("sqlite3"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for range 4 {
+				t.Run("Isolated", func(t *testing.T) {
+					t.Parallel()
+					dbx := sqlmodtest.New(t, f, sqlmod.WithMigrations(migrations))
+					_, err := dbx.DB().Exec(`INSERT INTO items VALUES (?)`, t.Name())
+					require.NoError(t, err)
+					n := 0
+					require.NoError(t, dbx.DB().QueryRow(`SELECT count(*) FROM items`).Scan(&n))
+					require.Equal(t, 1, n)
+				})
+			}
+		})
+	}
+}
+
+func TestNew_Stopped(t *testing.T) {
+	var dbx *sqlmod.DB
+	t.Run("Test", func(t *testing.T) {
+		dbx = sqlmodtest.New(t, sqlmodtest.SQLite("sqlite3"))
+		require.NoError(t, dbx.DB().Ping())
+	})
+	require.ErrorContains(t, dbx.DB().Ping(), "database is closed")
+}
+
+func TestPostgres(t *testing.T) {
+	fake.reset()
+	var dbName string
+	t.Run("Test", func(t *testing.T) {
+		sqlmodtest.New(t, sqlmodtest.Postgres("sqlmodtest-fake", "admin", "tmpl", func(name string) string {
+			dbName = name
+			return "db"
+		}))
+		require.Equal(t, []string{`CREATE DATABASE "` + dbName + `" TEMPLATE "tmpl"`}, fake.get())
+	})
+	require.Equal(t, []string{
+		`CREATE DATABASE "` + dbName + `" TEMPLATE "tmpl"`,
+		`DROP DATABASE IF EXISTS "` + dbName + `"`,
+	}, fake.get())
+}
+
+func init() {
+	sql.Register("sqlmodtest-fake", fake)
+}
+
+var fake = &fakeDriver{}
+
+// fakeDriver records statements run by connections to the DSN "admin".
+type fakeDriver struct {
+	mu    sync.Mutex
+	execs []string
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{d: d, admin: dsn == "admin"}, nil
+}
+
+func (d *fakeDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execs = nil
+}
+
+func (d *fakeDriver) get() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.execs
+}
+
+type fakeConn struct {
+	d     *fakeDriver
+	admin bool
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if c.admin {
+		c.d.mu.Lock()
+		defer c.d.mu.Unlock()
+		c.d.execs = append(c.d.execs, query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.ErrUnsupported }
@@ -0,0 +1,258 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	instrumentationName = "github.com/go-srvc/mods/sqlmod"
+	ErrInvalidStats     = errStr("slow query threshold and pool metrics interval must be positive")
+)
+
+// stats records query statistics. A nil *stats records nothing.
+type stats struct {
+	id       string
+	slow     time.Duration
+	logger   log.Logger
+	duration metric.Float64Histogram
+
+	waitCount    metric.Int64Counter
+	waitDuration metric.Float64Counter
+	lastWait     int64
+	lastWaitTime time.Duration
+}
+
+func newStats(id string, slow time.Duration, queryMetrics, poolMetrics bool) (*stats, error) {
+	if slow <= 0 && !queryMetrics && !poolMetrics {
+		return nil, nil
+	}
+	s := &stats{id: id, slow: slow}
+	if slow > 0 {
+		s.logger = global.Logger(instrumentationName)
+	}
+
+	m := otel.Meter(instrumentationName)
+	var errs [3]error
+	if queryMetrics {
+		s.duration, errs[0] = m.Float64Histogram("sqlmod.query.duration",
+			metric.WithUnit("s"),
+			metric.WithDescription("Duration of queries by fingerprint."),
+		)
+	}
+	if poolMetrics {
+		s.waitCount, errs[1] = m.Int64Counter("sqlmod.pool.wait_count",
+			metric.WithDescription("Number of connections waited for."),
+		)
+		s.waitDuration, errs[2] = m.Float64Counter("sqlmod.pool.wait_duration",
+			metric.WithUnit("s"),
+			metric.WithDescription("Time spent waiting for connections."),
+		)
+	}
+	if err := errors.Join(errs[:]...); err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	return s, nil
+}
+
+// tracksQueries reports whether queries must be recorded.
+func (s *stats) tracksQueries() bool {
+	return s != nil && (s.slow > 0 || s.duration != nil)
+}
+
+// query records a query that took d to run.
+func (s *stats) query(ctx context.Context, query string, args int, d time.Duration, err error) {
+	if s == nil || errors.Is(err, driver.ErrSkip) || (s.duration == nil && (s.slow <= 0 || d < s.slow)) {
+		return
+	}
+	normalized := Normalize(query)
+	fingerprint := Fingerprint(normalized)
+	if s.duration != nil {
+		s.duration.Record(ctx, d.Seconds(), metric.WithAttributes(
+			attribute.String("sqlmod.id", s.id),
+			attribute.String("db.query.fingerprint", fingerprint),
+		))
+	}
+	if s.slow <= 0 || d < s.slow {
+		return
+	}
+
+	r := log.Record{}
+	r.SetTimestamp(time.Now())
+	r.SetSeverity(log.SeverityWarn)
+	r.SetSeverityText("WARN")
+	r.SetBody(log.StringValue("Slow query"))
+	r.AddAttributes(
+		log.String("sqlmod.id", s.id),
+		log.String("db.query.fingerprint", fingerprint),
+		log.String("db.query.text", normalized),
+		log.Int("db.query.args", args),
+		log.Float64("duration", d.Seconds()),
+	)
+	if err != nil {
+		r.AddAttributes(log.String("error", err.Error()))
+	}
+	s.logger.Emit(ctx, r)
+}
+
+// pool records the growth of pool wait statistics since the last call.
+func (s *stats) pool(ctx context.Context, count int64, wait time.Duration) {
+	if s == nil || s.waitCount == nil {
+		return
+	}
+	attrs := metric.WithAttributes(attribute.String("sqlmod.id", s.id))
+	s.waitCount.Add(ctx, count-s.lastWait, attrs)
+	s.waitDuration.Add(ctx, (wait - s.lastWaitTime).Seconds(), attrs)
+	s.lastWait, s.lastWaitTime = count, wait
+}
+
+// Normalize returns the query with literals and placeholders replaced by ?,
+// lists of them collapsed into one, comments removed and whitespace
+// collapsed, so that queries differing only by their values are equal.
+func Normalize(query string) string {
+	b := strings.Builder{}
+	b.Grow(len(query))
+	space := false
+	var prev byte
+	write := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+		prev = s[len(s)-1]
+	}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+			space = true
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+			space = true
+		case c == '\'':
+			i = closingQuote(query, i)
+			write("?")
+		case c == '"' || c == '`':
+			end := closingQuote(query, i)
+			write(query[i:min(end+1, len(query))])
+			i = end
+		case isDigit(c) && (space || !identByte(prev)):
+			for i+1 < len(query) && (isDigit(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			write("?")
+		case (c == '$' || c == ':') && i+1 < len(query) && isDigit(query[i+1]):
+			for i+1 < len(query) && isDigit(query[i+1]) {
+				i++
+			}
+			write("?")
+		default:
+			write(query[i : i+1])
+		}
+	}
+	return collapseLists(b.String())
+}
+
+// closingQuote returns the index of the quote closing the one at start,
+// skipping doubled quotes used for escaping.
+func closingQuote(query string, start int) int {
+	q := query[start]
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != q {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == q {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(query)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// identByte reports whether c can be part of an identifier, so that a digit
+// following it like in t1 is not a number.
+func identByte(c byte) bool {
+	return isDigit(c) || c == '_' || (c|0x20 >= 'a' && c|0x20 <= 'z') || c >= utf8.RuneSelf
+}
+
+// collapseLists replaces (?, ?, ?) with (?).
+func collapseLists(s string) string {
+	for {
+		collapsed := strings.ReplaceAll(s, "?, ?", "?")
+		collapsed = strings.ReplaceAll(collapsed, "?,?", "?")
+		if collapsed == s {
+			return s
+		}
+		s = collapsed
+	}
+}
+
+// Fingerprint returns a short hash identifying a normalized query.
+func Fingerprint(normalized string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalized))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// WithSlowQueryLog logs queries taking at least threshold through the
+// global OpenTelemetry logger set by logmod. Records have the normalized
+// query, its fingerprint and the number of arguments, but no argument
+// values. Place logmod before the database.
+func WithSlowQueryLog(threshold time.Duration) Opt {
+	return func(d *DB) error {
+		if threshold <= 0 {
+			return ErrInvalidStats
+		}
+		d.slowQuery = threshold
+		return nil
+	}
+}
+
+// WithQueryMetrics records the duration of queries in a histogram by
+// query fingerprint, using the global meter provider set by metermod.
+func WithQueryMetrics() Opt {
+	return func(d *DB) error {
+		d.queryMetrics = true
+		return nil
+	}
+}
+
+// WithPoolMetrics records time spent waiting for connections of the pool
+// and the number of waits every interval while the module runs.
+func WithPoolMetrics(interval time.Duration) Opt {
+	return func(d *DB) error {
+		if interval <= 0 {
+			return ErrInvalidStats
+		}
+		d.poolInterval = interval
+		return nil
+	}
+}
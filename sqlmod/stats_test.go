@@ -0,0 +1,176 @@
+package sqlmod_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{query: "SELECT 1", want: "SELECT ?"},
+		{query: "SELECT * FROM t1 WHERE id = 42", want: "SELECT * FROM t1 WHERE id = ?"},
+		{query: "select *\n\tfrom t  where a = 'it''s' and b = 1.5", want: "select * from t where a = ? and b = ?"},
+		{query: "SELECT * FROM t WHERE id = $1 AND name = :2", want: "SELECT * FROM t WHERE id = ? AND name = ?"},
+		{query: "SELECT * FROM t WHERE id IN (1, 2, 3)", want: "SELECT * FROM t WHERE id IN (?)"},
+		{query: "INSERT INTO t VALUES (?,?,?)", want: "INSERT INTO t VALUES (?)"},
+		{query: "SELECT \"col1\" FROM t -- comment\nWHERE /* inline */ x = 'a'", want: "SELECT \"col1\" FROM t WHERE x = ?"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			require.Equal(t, tt.want, sqlmod.Normalize(tt.query))
+		})
+	}
+	require.Equal(t, sqlmod.Fingerprint("SELECT ?"), sqlmod.Fingerprint(sqlmod.Normalize("SELECT 2")))
+	require.NotEqual(t, sqlmod.Fingerprint("SELECT ?"), sqlmod.Fingerprint("SELECT ? FROM t"))
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	p := &recordProcessor{}
+	global.SetLoggerProvider(sdklog.NewLoggerProvider(sdklog.WithProcessor(p)))
+
+	dbx := newCounterDB(t, sqlmod.WithSlowQueryLog(time.Nanosecond))
+	_, err := dbx.DB().Exec(`UPDATE counter SET n = n + 1 WHERE n >= ?`, 0)
+	require.NoError(t, err)
+	_, err = dbx.DB().Exec(`SELECT * FROM missing WHERE id = 'secret'`)
+	require.Error(t, err)
+
+	records := p.get()
+	require.Len(t, records, 3, "including the table creation")
+	records = records[1:]
+	require.Equal(t, otellog.SeverityWarn, records[0].Severity())
+	require.Equal(t, "Slow query", records[0].Body().AsString())
+	attrs := attributes(records[0])
+	require.Equal(t, "UPDATE counter SET n = n + ? WHERE n >= ?", attrs["db.query.text"])
+	require.Equal(t, sqlmod.Fingerprint(attrs["db.query.text"]), attrs["db.query.fingerprint"])
+	require.Equal(t, "1", attrs["db.query.args"])
+	require.NotContains(t, attrs, "error")
+
+	attrs = attributes(records[1])
+	require.Equal(t, "SELECT * FROM missing WHERE id = ?", attrs["db.query.text"])
+	require.Contains(t, attrs["error"], "no such table")
+}
+
+func TestQueryMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	dbx := newCounterDB(t, sqlmod.WithQueryMetrics())
+	for id := range 3 {
+		_, err := dbx.DB().Exec(`SELECT n FROM counter WHERE n = ?`, id)
+		require.NoError(t, err)
+	}
+	stmt, err := dbx.DB().Prepare(`SELECT n FROM counter WHERE n = 1`)
+	require.NoError(t, err)
+	_, err = stmt.Exec()
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+
+	hist := collect(t, reader)["sqlmod.query.duration"].(metricdata.Histogram[float64])
+	counts := map[string]uint64{}
+	for _, dp := range hist.DataPoints {
+		require.Equal(t, 2, dp.Attributes.Len(), "only id and fingerprint")
+		fingerprint, _ := dp.Attributes.Value(attribute.Key("db.query.fingerprint"))
+		counts[fingerprint.AsString()] = dp.Count
+	}
+	require.Equal(t, uint64(4), counts[sqlmod.Fingerprint("SELECT n FROM counter WHERE n = ?")])
+}
+
+func TestPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db")),
+		sqlmod.WithMaxOpenConns(1),
+		sqlmod.WithPoolMetrics(time.Millisecond),
+	)
+	require.NoError(t, dbx.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(dbx.Run)
+
+	tx, err := dbx.DB().Begin()
+	require.NoError(t, err)
+	waited := make(chan error)
+	go func() {
+		_, err := dbx.DB().Exec(`SELECT 1`)
+		waited <- err
+	}()
+	require.Eventually(t, func() bool { return dbx.DB().Stats().WaitCount == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, tx.Commit())
+	require.NoError(t, <-waited)
+
+	require.Eventually(t, func() bool {
+		sum, ok := collect(t, reader)["sqlmod.pool.wait_count"].(metricdata.Sum[int64])
+		return ok && len(sum.DataPoints) == 1 && sum.DataPoints[0].Value == 1
+	}, time.Second, time.Millisecond)
+	require.NoError(t, dbx.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestStats_Errors(t *testing.T) {
+	dbx := sqlmod.New(sqlmod.WithDB(nil), sqlmod.WithSlowQueryLog(0))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidStats)
+
+	dbx = sqlmod.New(sqlmod.WithDB(nil), sqlmod.WithPoolMetrics(-time.Second))
+	require.ErrorIs(t, dbx.Init(), sqlmod.ErrInvalidStats)
+}
+
+type recordProcessor struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *recordProcessor) OnEmit(_ context.Context, r *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, r.Clone())
+	return nil
+}
+
+func (p *recordProcessor) get() []sdklog.Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.records
+}
+
+func (p *recordProcessor) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+func (p *recordProcessor) Shutdown(context.Context) error                         { return nil }
+func (p *recordProcessor) ForceFlush(context.Context) error                       { return nil }
+
+func attributes(r sdklog.Record) map[string]string {
+	attrs := map[string]string{}
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.String()
+		return true
+	})
+	return attrs
+}
+
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	t.Helper()
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
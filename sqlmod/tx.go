@@ -0,0 +1,133 @@
+package sqlmod
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	ErrTxPanic      = errStr("transaction panicked")
+	ErrInvalidRetry = errStr("retry attempts and backoff must be positive")
+)
+
+// RetryClassifier reports whether a transaction that failed with err can be
+// retried from the start.
+type RetryClassifier func(err error) bool
+
+// WithTx runs fn in a transaction and commits it if fn returns nil, or rolls
+// it back if fn returns an error or panics. A panic is returned as
+// ErrTxPanic. When the transaction fails with an error the retry classifier
+// accepts, it's retried with exponential backoff, so fn must not have side
+// effects outside the transaction. See WithTxRetry and WithRetryClassifier.
+// Read-only transactions run on a replica chosen by Reader.
+func (d *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	backoff := d.txMin
+	for attempt := 1; ; attempt++ {
+		err := d.tx(ctx, opts, fn)
+		if err == nil || attempt >= d.txAttempts || errors.Is(err, ErrTxPanic) || !d.retryable(err) {
+			return err
+		}
+		// Half of the delay is random to spread out transactions that keep
+		// conflicting with each other.
+		t := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-t.C:
+			backoff = min(backoff*2, d.txMax)
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+func (d *DB) tx(ctx context.Context, opts *sql.TxOptions, fn func(*sql.Tx) error) (err error) {
+	db := d.db
+	if opts != nil && opts.ReadOnly {
+		db = d.Reader()
+	}
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.Join(fmt.Errorf("%w: %v", ErrTxPanic, p), rollback(tx))
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return errors.Join(err, rollback(tx))
+	}
+	return tx.Commit()
+}
+
+// rollback ignores the error of a transaction already rolled back because
+// its context was canceled.
+func rollback(tx *sql.Tx) error {
+	if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
+
+// RetryDefault accepts errors accepted by RetryPostgres or RetrySQLite.
+func RetryDefault(err error) bool {
+	return RetryPostgres(err) || RetrySQLite(err)
+}
+
+// RetryPostgres accepts serialization failures and deadlocks reported by
+// Postgres drivers exposing the SQLSTATE code, like pgx and lib/pq.
+func RetryPostgres(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.SQLState() {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
+
+// RetrySQLite accepts SQLITE_BUSY and SQLITE_LOCKED errors. Drivers
+// exposing the result code, like modernc.org/sqlite, are checked by code and
+// others by the error message.
+func RetrySQLite(err error) bool {
+	var codeErr interface{ Code() int }
+	if errors.As(err, &codeErr) {
+		switch codeErr.Code() & 0xff {
+		case 5, 6:
+			return true
+		}
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
+
+// WithTxRetry sets how many times WithTx runs a transaction failing with a
+// retryable error, and the backoff before the first retry, doubled after
+// each failure up to maxDelay. Default is 3 attempts with backoff growing
+// from 10ms up to 1s.
+func WithTxRetry(attempts int, initial, maxDelay time.Duration) Opt {
+	return func(d *DB) error {
+		if attempts < 1 || initial <= 0 || maxDelay < initial {
+			return ErrInvalidRetry
+		}
+		d.txAttempts, d.txMin, d.txMax = attempts, initial, maxDelay
+		return nil
+	}
+}
+
+// WithRetryClassifier sets which errors make WithTx retry the transaction.
+// Default is RetryDefault.
+func WithRetryClassifier(fn RetryClassifier) Opt {
+	return func(d *DB) error {
+		d.retryable = fn
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package sqlmod_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/sqlmod"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	dbx := newCounterDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, dbx.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE counter SET n = n + 1`)
+		return err
+	}))
+	require.Equal(t, 1, count(t, dbx))
+
+	errFn := errors.New("fn failed")
+	require.ErrorIs(t, dbx.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE counter SET n = n + 1`)
+		require.NoError(t, err)
+		return errFn
+	}), errFn)
+	require.Equal(t, 1, count(t, dbx), "rolled back")
+
+	err := dbx.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE counter SET n = n + 1`)
+		require.NoError(t, err)
+		panic("boom")
+	})
+	require.ErrorIs(t, err, sqlmod.ErrTxPanic)
+	require.ErrorContains(t, err, "boom")
+	require.Equal(t, 1, count(t, dbx), "rolled back")
+}
+
+func TestWithTx_Retry(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "Recovers", failures: 2, err: sqlStateErr("40001"), wantCalls: 3},
+		{name: "Exhausted", failures: 5, err: sqlStateErr("40P01"), wantCalls: 3, wantErr: true},
+		{name: "NotRetryable", failures: 1, err: sqlStateErr("23505"), wantCalls: 1, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dbx := newCounterDB(t, sqlmod.WithTxRetry(3, time.Millisecond, 2*time.Millisecond))
+			calls := 0
+			err := dbx.WithTx(context.Background(), nil, func(tx *sql.Tx) error {
+				calls++
+				if _, err := tx.Exec(`UPDATE counter SET n = n + 1`); err != nil {
+					return err
+				}
+				if calls <= tt.failures {
+					return fmt.Errorf("update: %w", tt.err)
+				}
+				return nil
+			})
+			require.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr {
+				require.ErrorIs(t, err, tt.err)
+				require.Equal(t, 0, count(t, dbx))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, 1, count(t, dbx))
+			}
+		})
+	}
+}
+
+func TestWithTx_RetryCanceled(t *testing.T) {
+	dbx := newCounterDB(t, sqlmod.WithTxRetry(3, time.Hour, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := dbx.WithTx(ctx, nil, func(*sql.Tx) error { return sqlStateErr("40001") })
+	require.ErrorIs(t, err, sqlStateErr("40001"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithTx_Classifier(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	holder := newCounterDB(t, sqlmod.WithDSN("sqlite3", dsn))
+	lock, err := holder.DB().Begin()
+	require.NoError(t, err)
+	_, err = lock.Exec(`UPDATE counter SET n = 10`)
+	require.NoError(t, err)
+
+	classified := 0
+	dbx := sqlmod.New(
+		sqlmod.WithDSN("sqlite3", dsn+"?_busy_timeout=0"),
+		sqlmod.WithTxRetry(2, time.Millisecond, time.Millisecond),
+		sqlmod.WithRetryClassifier(func(err error) bool {
+			classified++
+			sqliteErr := sqlite3.Error{}
+			if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy {
+				require.NoError(t, lock.Commit())
+				return true
+			}
+			return false
+		}),
+	)
+	require.NoError(t, dbx.Init())
+	t.Cleanup(func() { _ = dbx.Stop() })
+
+	require.NoError(t, dbx.WithTx(context.Background(), nil, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE counter SET n = n + 1`)
+		return err
+	}))
+	require.Equal(t, 1, classified)
+	require.Equal(t, 11, count(t, dbx))
+}
+
+func TestRetryClassifiers(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		postgres bool
+		sqlite   bool
+	}{
+		{err: fmt.Errorf("wrapped: %w", sqlStateErr("40001")), postgres: true},
+		{err: sqlStateErr("40P01"), postgres: true},
+		{err: sqlStateErr("23505")},
+		{err: sqlite3.Error{Code: sqlite3.ErrBusy}, sqlite: true},
+		{err: errors.New("database table is locked: users"), sqlite: true},
+		{err: codeErr(5 | 2<<8), sqlite: true},
+		{err: codeErr(6), sqlite: true},
+		{err: codeErr(19)},
+		{err: errors.New("connection refused")},
+	} {
+		require.Equal(t, tt.postgres, sqlmod.RetryPostgres(tt.err), tt.err)
+		require.Equal(t, tt.sqlite, sqlmod.RetrySQLite(tt.err), tt.err)
+		require.Equal(t, tt.postgres || tt.sqlite, sqlmod.RetryDefault(tt.err), tt.err)
+	}
+}
+
+func TestWithTxRetry_Invalid(t *testing.T) {
+	for _, opt := range []sqlmod.Opt{
+		sqlmod.WithTxRetry(0, time.Millisecond, time.Second),
+		sqlmod.WithTxRetry(3, 0, time.Second),
+		sqlmod.WithTxRetry(3, time.Second, time.Millisecond),
+	} {
+		require.ErrorIs(t, sqlmod.New(sqlmod.WithDB(&sql.DB{}), opt).Init(), sqlmod.ErrInvalidRetry)
+	}
+}
+
+type sqlStateErr string
+
+func (e sqlStateErr) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateErr) SQLState() string { return string(e) }
+
+type codeErr int
+
+func (e codeErr) Error() string { return fmt.Sprintf("code %d", int(e)) }
+func (e codeErr) Code() int     { return int(e) }
+
+func newCounterDB(t *testing.T, opts ...sqlmod.Opt) *sqlmod.DB {
+	opts = append([]sqlmod.Opt{sqlmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db"))}, opts...)
+	dbx := sqlmod.New(opts...)
+	require.NoError(t, dbx.Init())
+	t.Cleanup(func() { _ = dbx.Stop() })
+	_, err := dbx.DB().Exec(`CREATE TABLE IF NOT EXISTS counter (n INTEGER NOT NULL); INSERT INTO counter VALUES (0)`)
+	require.NoError(t, err)
+	return dbx
+}
+
+func count(t *testing.T, dbx *sqlmod.DB) int {
+	n := 0
+	require.NoError(t, dbx.DB().QueryRow(`SELECT n FROM counter`).Scan(&n))
+	return n
+}
@@ -2,8 +2,11 @@
 package sqlxmod
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
@@ -24,6 +27,10 @@ type DB struct {
 	dbx  *sqlx.DB
 	done chan struct{}
 	opts []Opt
+
+	migrations  fs.FS
+	migrateOpts []MigrateOpt
+	migrator    *Migrator
 }
 
 // New creates new sqlx module with given options.
@@ -34,6 +41,7 @@ func New(opts ...Opt) *DB {
 
 func (d *DB) Init() error {
 	d.done = make(chan struct{})
+	d.migrations, d.migrateOpts, d.migrator = nil, nil, nil
 	for _, opt := range d.opts {
 		if err := opt(d); err != nil {
 			return fmt.Errorf("failed to apply option: %w", err)
@@ -44,6 +52,17 @@ func (d *DB) Init() error {
 		return ErrDBNotSet
 	}
 
+	if d.migrations != nil {
+		opts := d.migrateOpts
+		if sqlx.BindType(d.dbx.DriverName()) == sqlx.DOLLAR {
+			opts = append([]MigrateOpt{WithMigrationDollarPlaceholders()}, opts...)
+		}
+		// migrate.go is a copy of the sqlmod migrator, see make migrate-check.
+		d.migrator = NewMigrator(d.dbx.DB, d.migrations, opts...)
+		if _, err := d.migrator.Up(context.Background()); err != nil {
+			return errors.Join(err, d.dbx.Close())
+		}
+	}
 	return nil
 }
 
@@ -62,6 +81,10 @@ func (d *DB) ID() string { return ID }
 // DB returns the underlying *sqlx.DB. Only valid after Init has run.
 func (d *DB) DB() *sqlx.DB { return d.dbx }
 
+// Migrator returns the migrator set up by WithMigrations, or nil without
+// migrations. Only valid after Init has run.
+func (d *DB) Migrator() *Migrator { return d.migrator }
+
 type Opt func(*DB) error
 
 // WithDSN opens a *sqlx.DB from the given driver name and DSN.
@@ -119,3 +142,14 @@ func WithOtel(driver, dsn string, opts ...otelsql.Option) Opt {
 		return WithDB(db, driver)(d)
 	}
 }
+
+// WithMigrations applies pending migrations from fsys during Init. See
+// Migrator for the file layout and locking. Placeholders are chosen from the
+// driver name. Init fails with ErrMigrationFailed if a migration fails.
+func WithMigrations(fsys fs.FS, opts ...MigrateOpt) Opt {
+	return func(d *DB) error {
+		d.migrations = fsys
+		d.migrateOpts = opts
+		return nil
+	}
+}
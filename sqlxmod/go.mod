@@ -7,6 +7,7 @@ require (
 	github.com/go-srvc/srvc v1.4.0
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/lib/pq v1.12.3
+	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/stretchr/testify v1.11.1
 	go.opentelemetry.io/otel v1.44.0
 )
@@ -0,0 +1,40 @@
+package sqlxmod_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-srvc/mods/sqlxmod"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations := fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)},
+		"0001_create_users.down.sql": {Data: []byte(`DROP TABLE users;`)},
+		"0002_add_email.up.sql":      {Data: []byte(`ALTER TABLE users ADD COLUMN email TEXT;`)},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbx := sqlxmod.New(
+		sqlxmod.WithDSN("sqlite3", filepath.Join(t.TempDir(), "test.db")),
+		sqlxmod.WithMigrations(migrations, sqlxmod.WithMigrationLogger(logger)),
+	)
+	require.NoError(t, dbx.Init())
+	_, err := dbx.DB().Exec(`INSERT INTO users (name, email) VALUES ('alice', 'alice@example.com')`)
+	require.NoError(t, err)
+
+	statuses, err := dbx.Migrator().Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.False(t, statuses[1].Pending())
+
+	_, err = dbx.Migrator().Down(context.Background())
+	require.ErrorIs(t, err, sqlxmod.ErrInvalidMigration, "no down file")
+	require.NoError(t, dbx.Stop())
+}
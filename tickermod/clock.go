@@ -0,0 +1,72 @@
+package tickermod
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts time for the ticker. The default uses package time.
+// See tickermodtest for a fake clock that is advanced by hand.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) ClockTicker
+}
+
+// ClockTicker is the subset of time.Ticker returned by Clock.NewTicker.
+type ClockTicker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) ClockTicker  { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// withTimeout is context.WithTimeout driven by clock c.
+func withTimeout(parent context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(realClock); ok {
+		return context.WithTimeout(parent, d)
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	deadline := c.After(d)
+	go func() {
+		select {
+		case <-deadline:
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return &clockCtx{Context: ctx, deadline: c.Now().Add(d)}, func() { cancel(context.Canceled) }
+}
+
+type clockCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *clockCtx) Deadline() (time.Time, bool) { return c.deadline, true }
+
+func (c *clockCtx) Err() error {
+	if c.Context.Err() == nil {
+		return nil
+	}
+	return context.Cause(c.Context)
+}
+
+// sleep waits for d or until ctx is done and reports whether d elapsed.
+func sleep(ctx context.Context, c Clock, d time.Duration) bool {
+	select {
+	case <-c.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package tickermod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClockInterval(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	start := clock.Now()
+	ticks := make(chan time.Time)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithFireOnStart(),
+		tickermod.WithFunc(func() error {
+			ticks <- clock.Now()
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	require.Equal(t, start, <-ticks)
+	for i := 1; i <= 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		require.Equal(t, start.Add(time.Duration(i)*time.Minute), <-ticks)
+	}
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestFakeClockTickTimeout(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	deadline := make(chan time.Time, 1)
+	hit := make(chan error, 1)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithTickTimeout(time.Minute),
+		tickermod.WithFuncCtx(func(ctx context.Context) error {
+			d, _ := ctx.Deadline()
+			deadline <- d
+			<-ctx.Done()
+			hit <- ctx.Err()
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	require.Equal(t, clock.Now().Add(time.Minute), <-deadline)
+	clock.Advance(time.Minute)
+	require.ErrorIs(t, <-hit, context.DeadlineExceeded)
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestFakeClockRetryBackoff(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	calls := make(chan time.Time)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithErrorPolicy(tickermod.RetryOnError(3, time.Second)),
+		tickermod.WithLogger(discardLogger),
+		tickermod.WithFunc(func() error {
+			calls <- clock.Now()
+			return errTick
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	runErr := make(chan error, 1)
+	go func() { runErr <- tickerMod.Run() }()
+
+	start := <-calls
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	require.Equal(t, start.Add(time.Second), <-calls)
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	require.Equal(t, start.Add(3*time.Second), <-calls)
+
+	require.ErrorIs(t, <-runErr, errTick)
+	require.NoError(t, tickerMod.Stop())
+}
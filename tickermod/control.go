@@ -0,0 +1,91 @@
+package tickermod
+
+import (
+	"context"
+	"time"
+)
+
+// Trigger runs the tick immediately and waits for it to return, applying
+// the error policy for retries. The error of the last tick function call is
+// returned, but it never stops the ticker. Overlap rules are respected: with
+// OverlapQueue Trigger waits for running ticks to finish, otherwise it
+// returns ErrTickRunning when no slot is free. The tick context is canceled
+// when either ctx is done or the ticker is stopped. Trigger works while the
+// ticker is paused.
+func (t *Ticker) Trigger(ctx context.Context) error {
+	if t.ctx.Err() != nil {
+		return ErrTickerStopped
+	}
+	if t.overlap.mode == overlapQueue {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.ctx.Done():
+			return ErrTickerStopped
+		}
+	} else {
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			return ErrTickRunning
+		}
+	}
+	defer func() { <-t.slots }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(t.ctx, cancel)
+	defer stop()
+	err, _ := t.tick(ctx, t.clock.Now())
+	return err
+}
+
+// Pause skips scheduled activations until Resume is called. Running ticks
+// are not interrupted.
+func (t *Ticker) Pause() { t.paused.Store(true) }
+
+// Resume continues scheduled activations after Pause. Activations that
+// passed while paused are not run.
+func (t *Ticker) Resume() { t.paused.Store(false) }
+
+// Paused reports whether scheduled activations are paused.
+func (t *Ticker) Paused() bool { return t.paused.Load() }
+
+// Trigger runs the named job immediately, see Ticker.Trigger.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	t, err := s.ticker(name)
+	if err != nil {
+		return err
+	}
+	return t.Trigger(ctx)
+}
+
+// Pause pauses the named job, see Ticker.Pause.
+func (s *Scheduler) Pause(name string) error {
+	t, err := s.ticker(name)
+	if err != nil {
+		return err
+	}
+	t.Pause()
+	return nil
+}
+
+// Resume resumes the named job, see Ticker.Resume.
+func (s *Scheduler) Resume(name string) error {
+	t, err := s.ticker(name)
+	if err != nil {
+		return err
+	}
+	t.Resume()
+	return nil
+}
+
+// SetInterval changes the interval of the named job, see Ticker.SetInterval.
+func (s *Scheduler) SetInterval(name string, d time.Duration) error {
+	t, err := s.ticker(name)
+	if err != nil {
+		return err
+	}
+	return t.SetInterval(d)
+}
@@ -0,0 +1,209 @@
+package tickermod_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrigger(t *testing.T) {
+	calls := 0
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithErrorPolicy(tickermod.RetryOnError(2, time.Millisecond)),
+		tickermod.WithLogger(discardLogger),
+		tickermod.WithFunc(func() error {
+			calls++
+			return errTick
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	require.ErrorIs(t, tickerMod.Trigger(context.Background()), errTick)
+	require.Equal(t, 2, calls, "retried by the error policy")
+	require.ErrorIs(t, tickerMod.Status().LastError, errTick)
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait(), "trigger errors don't stop the ticker")
+	require.ErrorIs(t, tickerMod.Trigger(context.Background()), tickermod.ErrTickerStopped)
+}
+
+func TestTriggerOverlap(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		overlap tickermod.OverlapPolicy
+		wantErr error
+	}{
+		{name: "Skip", overlap: tickermod.OverlapSkip(), wantErr: tickermod.ErrTickRunning},
+		{name: "Concurrent", overlap: tickermod.OverlapConcurrent(1), wantErr: tickermod.ErrTickRunning},
+		{name: "Queue", overlap: tickermod.OverlapQueue()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			tickerMod := tickermod.New(
+				tickermod.WithInterval(time.Hour),
+				tickermod.WithFireOnStart(),
+				tickermod.WithOverlap(tt.overlap),
+				tickermod.WithFunc(func() error {
+					started <- struct{}{}
+					<-release
+					return nil
+				}),
+			)
+			require.NoError(t, tickerMod.Init())
+			wg := &errgroup.ErrGroup{}
+			wg.Go(tickerMod.Run)
+			<-started
+
+			triggered := make(chan error)
+			go func() { triggered <- tickerMod.Trigger(context.Background()) }()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, <-triggered, tt.wantErr)
+				close(release)
+			} else {
+				select {
+				case <-started:
+					t.Fatal("trigger must wait for the running tick")
+				case <-time.After(10 * time.Millisecond):
+				}
+				close(release)
+				<-started
+				require.NoError(t, <-triggered)
+			}
+
+			require.NoError(t, tickerMod.Stop())
+			require.NoError(t, wg.Wait())
+		})
+	}
+}
+
+func TestTriggerCanceled(t *testing.T) {
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFuncCtx(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, tickerMod.Trigger(ctx), context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = tickerMod.Stop()
+	}()
+	require.NoError(t, tickerMod.Trigger(context.Background()), "canceled by Stop")
+}
+
+func TestPauseResume(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	calls := make(chan struct{}, 1)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithFunc(func() error {
+			calls <- struct{}{}
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	tickerMod.Pause()
+	require.True(t, tickerMod.Paused())
+	require.True(t, tickerMod.Status().Paused)
+	for range 3 {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	require.Empty(t, calls)
+	require.Zero(t, tickerMod.Missed(), "paused activations are not missed")
+
+	tickerMod.Resume()
+	require.False(t, tickerMod.Paused())
+	clock.Advance(time.Minute)
+	<-calls
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestSchedulerHandler(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := tickermod.NewScheduler(
+		tickermod.WithDefaults(tickermod.WithClock(clock), tickermod.WithLocation(time.UTC)),
+		tickermod.WithJob("ok", tickermod.WithInterval(time.Hour), tickermod.WithFunc(func() error { return nil })),
+		tickermod.WithJob("broken", tickermod.WithInterval(time.Hour), tickermod.WithFunc(func() error { return errTick })),
+	)
+	require.NoError(t, s.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(s.Run)
+	clock.BlockUntil(2)
+
+	srv := httptest.NewServer(http.StripPrefix("/jobs", s.Handler()))
+	defer srv.Close()
+
+	do := func(method, path string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	code, _ := do(http.MethodPost, "/jobs/ok/trigger")
+	require.Equal(t, http.StatusNoContent, code)
+	code, body := do(http.MethodPost, "/jobs/broken/trigger")
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.Equal(t, errTick.Error(), body)
+	code, _ = do(http.MethodPost, "/jobs/missing/trigger")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do(http.MethodPost, "/jobs/ok/pause")
+	require.Equal(t, http.StatusNoContent, code)
+
+	code, body = do(http.MethodGet, "/jobs/ok")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{
+		"name": "ok",
+		"next_run": "2024-01-01T01:00:00Z",
+		"last_run": "2024-01-01T00:00:00Z",
+		"missed": 0,
+		"paused": true
+	}`, body)
+
+	code, body = do(http.MethodGet, "/jobs/")
+	require.Equal(t, http.StatusOK, code)
+	statuses := []map[string]any{}
+	require.NoError(t, json.Unmarshal([]byte(body), &statuses))
+	require.Len(t, statuses, 2)
+	require.Equal(t, "broken", statuses[0]["name"])
+	require.Equal(t, errTick.Error(), statuses[0]["last_error"])
+
+	code, _ = do(http.MethodPost, "/jobs/ok/resume")
+	require.Equal(t, http.StatusNoContent, code)
+	require.False(t, s.Statuses()[1].Paused)
+
+	require.NoError(t, s.Stop())
+	require.NoError(t, wg.Wait())
+}
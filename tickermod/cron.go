@@ -0,0 +1,244 @@
+package tickermod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule computes the next activation strictly after given time.
+// A zero time means the schedule never fires again.
+type schedule interface {
+	next(after time.Time) time.Time
+}
+
+type interval time.Duration
+
+func (i interval) next(after time.Time) time.Time { return after.Add(time.Duration(i)) }
+
+// cronSchedule matches wall clock time in the location of the time passed to next.
+type cronSchedule struct {
+	sec, min, hour, dom, month, dow uint64
+	domStar, dowStar                bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{min: 0, max: 59}
+	minuteField = cronField{min: 0, max: 59}
+	hourField   = cronField{min: 0, max: 23}
+	domField    = cronField{min: 1, max: 31}
+	monthField  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as an alias for sunday and folded to 0 after parsing.
+	dowField = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// parseCron parses cron expression with optional leading seconds field.
+// Five fields are read as "min hour dom month dow" with seconds set to zero.
+// Descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight,
+// @hourly and @every <duration> are also accepted.
+func parseCron(expr string) (schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		dur, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+		}
+		if dur <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, expr)
+		}
+		return interval(dur), nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		spec, ok := cronDescriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown descriptor %q", ErrInvalidSchedule, expr)
+		}
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, got %d", ErrInvalidSchedule, len(fields))
+	}
+
+	c := &cronSchedule{}
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		def  cronField
+	}{
+		{&c.sec, secondField},
+		{&c.min, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	} {
+		if *f.bits, err = f.def.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidSchedule, fields[i], err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[3] == "*" || fields[3] == "?"
+	c.dowStar = fields[5] == "*" || fields[5] == "?"
+
+	if c.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: %q never fires", ErrInvalidSchedule, expr)
+	}
+	return c, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next walks wall clock time forward from after until every field matches.
+// Calendar arithmetic is done in UTC so DST never shifts the walk, and only
+// the match is converted back to after's location. As a result wall clock
+// times skipped by a DST gap fire shifted forward by the gap, and times
+// repeated when clocks go back fire only on their first occurrence.
+func (c *cronSchedule) next(after time.Time) time.Time {
+	loc := after.Location()
+	w := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), after.Second(), 0, time.UTC).Add(time.Second)
+
+	for limit := w.AddDate(5, 0, 0); w.Before(limit); {
+		switch {
+		case c.month&(1<<uint(w.Month())) == 0:
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(w):
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(w.Hour())) == 0:
+			w = w.Truncate(time.Hour).Add(time.Hour)
+		case c.min&(1<<uint(w.Minute())) == 0:
+			w = w.Truncate(time.Minute).Add(time.Minute)
+		case c.sec&(1<<uint(w.Second())) == 0:
+			w = w.Add(time.Second)
+		default:
+			t := inLocation(w, loc)
+			if t.After(after) {
+				return t
+			}
+			w = w.Add(time.Second)
+		}
+	}
+	return time.Time{}
+}
+
+// inLocation converts wall clock time w to an instant in loc using the
+// offsets of loc on both sides of the nearest transition, without relying
+// on how time.Date resolves ambiguous times. Wall clock times repeated when
+// clocks go back resolve to their first occurrence, and times that don't
+// exist because of a DST gap are shifted forward by the length of the gap.
+func inLocation(w time.Time, loc *time.Location) time.Time {
+	wall := w.UTC()
+	// Offsets are within [-12h, +14h], so the instant lies in this window.
+	_, before := wall.Add(-14 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(14 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if _, o := t.Zone(); o == offset && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if first.IsZero() {
+		// Inside the gap: read the wall clock with the offset before it.
+		first = wall.Add(-time.Duration(before) * time.Second).In(loc)
+	}
+	return first
+}
+
+// dayMatches follows cron convention where restricting both day of month and
+// day of week matches either of them.
+func (c *cronSchedule) dayMatches(w time.Time) bool {
+	dom := c.dom&(1<<uint(w.Day())) != 0
+	dow := c.dow&(1<<uint(w.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
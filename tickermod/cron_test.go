@@ -0,0 +1,229 @@
+package tickermod_test
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestScheduleNext(t *testing.T) {
+	utc := time.UTC
+	at := func(s string) time.Time {
+		ts, err := time.ParseInLocation(time.DateTime, s, utc)
+		require.NoError(t, err)
+		return ts
+	}
+
+	tests := []struct {
+		expr  string
+		after string
+		want  string
+	}{
+		{"0 */5 * * * *", "2024-01-01 10:02:03", "2024-01-01 10:05:00"},
+		{"*/5 * * * *", "2024-01-01 10:05:00", "2024-01-01 10:10:00"},
+		{"30 * * * * *", "2024-01-01 10:00:30", "2024-01-01 10:01:30"},
+		{"0 0 9 * * mon-fri", "2024-01-05 10:00:00", "2024-01-08 09:00:00"},
+		{"0 0 0 1 JAN *", "2024-06-01 00:00:00", "2025-01-01 00:00:00"},
+		{"0 0 0 29 2 *", "2024-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"0 0 12 13 * 5", "2024-01-01 00:00:00", "2024-01-05 12:00:00"},
+		{"0 0 0 * * 7", "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{"0 15,45 1-3/2 * * *", "2024-01-01 01:50:00", "2024-01-01 03:15:00"},
+		{"@daily", "2024-01-01 00:00:00", "2024-01-02 00:00:00"},
+		{"@hourly", "2024-01-01 10:59:59", "2024-01-01 11:00:00"},
+		{"@weekly", "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{"@monthly", "2024-01-31 00:00:00", "2024-02-01 00:00:00"},
+		{"@yearly", "2024-01-31 00:00:00", "2025-01-01 00:00:00"},
+		{"@every 90s", "2024-01-01 00:00:00", "2024-01-01 00:01:30"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			next, err := tickermod.NextActivation(tc.expr, at(tc.after))
+			require.NoError(t, err)
+			require.Equal(t, at(tc.want), next)
+		})
+	}
+}
+
+func TestScheduleDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	// 2024-03-10 02:00 EST jumps to 03:00 EDT.
+	// 2024-11-03 02:00 EDT falls back to 01:00 EST.
+	springGap := time.Date(2024, 3, 10, 1, 59, 59, 0, ny)
+	fallBack := time.Date(2024, 11, 3, 0, 59, 59, 0, ny)
+	firstOneThirty := time.Date(2024, 11, 3, 1, 30, 0, 0, ny)
+	// time.Date resolves times in the gap of these zones the other way.
+	// 2024-03-31 02:00 CET jumps to 03:00 CEST, 2024-10-27 03:00 CEST
+	// falls back to 02:00 CET.
+	berlin := mustLoad(t, "Europe/Berlin")
+	// 2024-03-31 01:00 GMT jumps to 02:00 BST.
+	london := mustLoad(t, "Europe/London")
+	// 2024-10-06 02:00 AEST jumps to 03:00 AEDT, 2024-04-07 03:00 AEDT
+	// falls back to 02:00 AEST.
+	sydney := mustLoad(t, "Australia/Sydney")
+
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "SkippedTimeShiftsForward",
+			expr:  "0 30 2 * * *",
+			after: springGap,
+			want:  time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+		},
+		{
+			name:  "SkippedTimeNextDay",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 3, 10, 3, 30, 0, 0, ny),
+			want:  time.Date(2024, 3, 11, 6, 30, 0, 0, time.UTC), // 02:30 EDT
+		},
+		{
+			name:  "GapAfterTimeUnaffected",
+			expr:  "0 0 3 * * *",
+			after: springGap,
+			want:  time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), // 03:00 EDT
+		},
+		{
+			name:  "RepeatedTimeFiresOnFirstOccurrence",
+			expr:  "0 30 1 * * *",
+			after: fallBack,
+			want:  time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+		},
+		{
+			name:  "RepeatedTimeDoesNotFireTwice",
+			expr:  "0 30 1 * * *",
+			after: firstOneThirty,
+			want:  time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC), // 01:30 EST next day
+		},
+		{
+			name:  "HourlyResumesAfterRepeatedHour",
+			expr:  "0 0 * * * *",
+			after: firstOneThirty,
+			want:  time.Date(2024, 11, 3, 7, 0, 0, 0, time.UTC), // 02:00 EST
+		},
+		{
+			name:  "BerlinSkippedTimeShiftsForward",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+			want:  time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+		},
+		{
+			name:  "BerlinBeforeGapUnaffected",
+			expr:  "0 30 1 * * *",
+			after: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+			want:  time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), // 01:30 CET
+		},
+		{
+			name:  "BerlinRepeatedTimeFiresOnFirstOccurrence",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 10, 27, 1, 59, 59, 0, berlin),
+			want:  time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+		},
+		{
+			name:  "BerlinRepeatedTimeDoesNotFireTwice",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).In(berlin),
+			want:  time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC), // 02:30 CET next day
+		},
+		{
+			name:  "LondonSkippedTimeShiftsForward",
+			expr:  "0 30 1 * * *",
+			after: time.Date(2024, 3, 31, 0, 0, 0, 0, london),
+			want:  time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), // 02:30 BST
+		},
+		{
+			name:  "SydneySkippedTimeShiftsForward",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 10, 6, 0, 0, 0, 0, sydney),
+			want:  time.Date(2024, 10, 5, 16, 30, 0, 0, time.UTC), // 03:30 AEDT
+		},
+		{
+			name:  "SydneyRepeatedTimeFiresOnFirstOccurrence",
+			expr:  "0 30 2 * * *",
+			after: time.Date(2024, 4, 7, 0, 0, 0, 0, sydney),
+			want:  time.Date(2024, 4, 6, 15, 30, 0, 0, time.UTC), // 02:30 AEDT
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next, err := tickermod.NextActivation(tc.expr, tc.after)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, next.UTC())
+		})
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"5-1 * * * * *",
+		"x * * * * *",
+		"0 0 0 30 2 *",
+		"@never",
+		"@every -1s",
+		"@every soon",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := tickermod.NextActivation(expr, time.Now())
+			require.ErrorIs(t, err, tickermod.ErrInvalidSchedule)
+		})
+	}
+}
+
+func TestWithSchedule(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ny := mustLoad(t, "America/New_York")
+		ticks := make(chan time.Time, 1)
+		tickerMod := tickermod.New(
+			tickermod.WithSchedule("0 0 9 * * *"),
+			tickermod.WithLocation(ny),
+			tickermod.WithFunc(func() error {
+				ticks <- time.Now()
+				return nil
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		for range 3 {
+			tick := (<-ticks).In(ny)
+			require.Equal(t, 9, tick.Hour())
+			require.Zero(t, tick.Minute())
+			require.Zero(t, tick.Second())
+		}
+
+		require.NoError(t, tickerMod.Stop())
+		require.NoError(t, wg.Wait())
+	})
+}
+
+func TestWithScheduleInvalid(t *testing.T) {
+	tickerMod := tickermod.New(
+		tickermod.WithSchedule("not a schedule"),
+		tickermod.WithFunc(func() error { return nil }),
+	)
+	require.ErrorIs(t, tickerMod.Init(), tickermod.ErrInvalidSchedule)
+}
@@ -0,0 +1,17 @@
+package tickermod
+
+import "time"
+
+// NextActivation parses expr and returns its first activation after given time.
+func NextActivation(expr string, after time.Time) (time.Time, error) {
+	s, err := parseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.next(after), nil
+}
+
+// RetryDelay returns the wait of p before retrying after attempt failed.
+func RetryDelay(p ErrorPolicy, attempt int) time.Duration {
+	return p.delay(attempt)
+}
@@ -0,0 +1,112 @@
+package tickermod
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Handler returns http.Handler exposing the ticker controls:
+//
+//	GET  /         status as JSON
+//	POST /trigger  Trigger, responds once the tick has returned
+//	POST /pause    Pause
+//	POST /resume   Resume
+//
+// Use http.StripPrefix to mount it under a path.
+func (t *Ticker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, t.Status())
+	})
+	mux.HandleFunc("POST /trigger", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, t.Trigger(r.Context()))
+	})
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		t.Pause()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		t.Resume()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// Handler returns http.Handler exposing the controls of every job:
+//
+//	GET  /               statuses of all jobs as JSON
+//	GET  /{name}         status of the job as JSON
+//	POST /{name}/trigger Trigger, responds once the tick has returned
+//	POST /{name}/pause   Pause
+//	POST /{name}/resume  Resume
+//
+// Use http.StripPrefix to mount it under a path.
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.Statuses())
+	})
+	mux.HandleFunc("GET /{name}", func(w http.ResponseWriter, r *http.Request) {
+		status, err := s.Status(r.PathValue("name"))
+		if err != nil {
+			writeResult(w, err)
+			return
+		}
+		writeJSON(w, status)
+	})
+	mux.HandleFunc("POST /{name}/trigger", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, s.Trigger(r.Context(), r.PathValue("name")))
+	})
+	mux.HandleFunc("POST /{name}/pause", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, s.Pause(r.PathValue("name")))
+	})
+	mux.HandleFunc("POST /{name}/resume", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, s.Resume(r.PathValue("name")))
+	})
+	return mux
+}
+
+// MarshalJSON encodes the status with snake_case keys, omitting zero times
+// and reporting LastError as a string.
+func (s Status) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Name      string    `json:"name"`
+		NextRun   time.Time `json:"next_run,omitzero"`
+		LastRun   time.Time `json:"last_run,omitzero"`
+		LastError string    `json:"last_error,omitempty"`
+		Missed    uint64    `json:"missed"`
+		Paused    bool      `json:"paused"`
+	}{
+		Name:    s.Name,
+		NextRun: s.NextRun,
+		LastRun: s.LastRun,
+		Missed:  s.Missed,
+		Paused:  s.Paused,
+	}
+	if s.LastError != nil {
+		v.LastError = s.LastError.Error()
+	}
+	return json.Marshal(v)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrTickRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrTickerStopped):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
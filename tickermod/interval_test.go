@@ -0,0 +1,109 @@
+package tickermod_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetInterval(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := tickermodtest.NewClock(t0)
+	calls := make(chan struct{}, 1)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithLocation(time.UTC),
+		tickermod.WithSchedule("@daily"),
+		tickermod.WithFunc(func() error {
+			calls <- struct{}{}
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	require.ErrorIs(t, tickerMod.SetInterval(0), tickermod.ErrInvalidInterval)
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	clock.Advance(30 * time.Second)
+	require.NoError(t, tickerMod.SetInterval(time.Minute))
+	// The wait for the daily activation is abandoned but stays on the clock.
+	clock.BlockUntil(2)
+	require.Equal(t, t0.Add(90*time.Second), tickerMod.Status().NextRun)
+
+	clock.Advance(time.Minute)
+	<-calls
+	clock.BlockUntil(2)
+	require.Equal(t, t0.Add(150*time.Second), tickerMod.Status().NextRun)
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestFuncNext(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := tickermodtest.NewClock(t0)
+	delays := make(chan time.Duration)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithLocation(time.UTC),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithFuncNext(func(context.Context) (time.Duration, error) {
+			return <-delays, nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	for _, step := range []struct {
+		delay time.Duration
+		next  time.Time
+	}{
+		{delay: 10 * time.Second, next: t0.Add(70 * time.Second)},
+		{delay: 0, next: t0.Add(130 * time.Second)},
+		{delay: 5 * time.Second, next: t0.Add(135 * time.Second)},
+	} {
+		clock.BlockUntil(1)
+		clock.Advance(tickerMod.Status().NextRun.Sub(clock.Now()))
+		delays <- step.delay
+		clock.BlockUntil(1)
+		require.Equal(t, step.next, tickerMod.Status().NextRun)
+	}
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestSetIntervalRace(t *testing.T) {
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Millisecond),
+		tickermod.WithOverlap(tickermod.OverlapConcurrent(4)),
+		tickermod.WithFuncNext(func(context.Context) (time.Duration, error) {
+			return time.Millisecond, nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	setters := sync.WaitGroup{}
+	for i := range 8 {
+		setters.Go(func() {
+			for j := range 100 {
+				require.NoError(t, tickerMod.SetInterval(time.Duration(i+j+1)*time.Microsecond))
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, tickerMod.Stop())
+	setters.Wait()
+	require.NoError(t, tickerMod.SetInterval(time.Second), "after Stop")
+	require.NoError(t, wg.Wait())
+}
@@ -0,0 +1,75 @@
+package tickermod
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Locker elects a single replica to run each activation. See tickersql
+// for an implementation backed by a database.
+type Locker interface {
+	// TryLock acquires or extends the lease on key for ttl on behalf of
+	// owner. It reports false if another owner holds an unexpired lease.
+	TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+}
+
+// lease holds the lock for the duration of a tick. The lease is taken for
+// the time until the next activation and is not released after the tick,
+// so replicas with a different phase can't run the same activation again.
+// It's renewed while the tick runs longer than that.
+func (t *Ticker) lease(ctx context.Context) (context.Context, func(), bool, error) {
+	now := t.clock.Now()
+	ttl := time.Second
+	if next := t.schedule().next(now.In(t.loc)); !next.IsZero() {
+		ttl = max(ttl, next.Sub(now))
+	}
+
+	ok, err := t.locker.TryLock(ctx, t.lockKey, t.owner, ttl)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%w: %w", ErrLockFailed, err)
+	}
+	if !ok {
+		return nil, nil, false, nil
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		tk := t.clock.NewTicker(ttl / 2)
+		defer tk.Stop()
+		for {
+			select {
+			case <-tk.C():
+				ok, err := t.locker.TryLock(ctx, t.lockKey, t.owner, ttl)
+				switch {
+				case err != nil:
+					t.log.Warn("Failed to renew tick lease", slog.String("id", t.name), slog.String("key", t.lockKey), slog.Any("error", err))
+				case !ok:
+					t.log.Warn("Tick lease lost", slog.String("id", t.name), slog.String("key", t.lockKey))
+					cancel(ErrLeaseLost)
+					return
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return ctx, func() {
+		close(done)
+		wg.Wait()
+		cancel(context.Canceled)
+	}, true, nil
+}
+
+func newOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), rand.Text()[:8])
+}
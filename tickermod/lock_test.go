@@ -0,0 +1,178 @@
+package tickermod_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockerSingleRunner(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	locker := tickermodtest.NewLocker(clock.Now)
+	runs := make(chan int, 10)
+
+	replicas := make([]*tickermod.Ticker, 3)
+	wg := &errgroup.ErrGroup{}
+	for i := range replicas {
+		replicas[i] = tickermod.New(
+			tickermod.WithClock(clock),
+			tickermod.WithSchedule("@every 1m"),
+			tickermod.WithLocker(locker, "job"),
+			tickermod.WithFunc(func() error {
+				runs <- i
+				return nil
+			}),
+		)
+		require.NoError(t, replicas[i].Init())
+		wg.Go(replicas[i].Run)
+	}
+
+	for range 5 {
+		clock.BlockUntil(len(replicas))
+		clock.Advance(time.Minute)
+		<-runs
+	}
+	// All replicas are waiting for the next activation, so every one of them
+	// had its chance for the last one.
+	clock.BlockUntil(len(replicas))
+	require.Empty(t, runs)
+
+	for _, r := range replicas {
+		require.NoError(t, r.Stop())
+	}
+	require.NoError(t, wg.Wait())
+}
+
+func TestLockerRenewsDuringLongTick(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	locker := &renewLocker{Locker: tickermodtest.NewLocker(clock.Now), calls: make(chan struct{}, 1)}
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithLocker(locker, "job"),
+		tickermod.WithFunc(func() error {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-locker.calls
+	<-started
+	owner := locker.Owner("job")
+	require.NotEmpty(t, owner)
+
+	// The lease lasts one interval and is renewed every half of it.
+	for range 6 {
+		clock.BlockUntil(1)
+		clock.Advance(30 * time.Second)
+		<-locker.calls
+	}
+	require.Equal(t, owner, locker.Owner("job"))
+	ok, err := locker.Locker.TryLock(context.Background(), "job", "other", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	close(release)
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestLockerLeaseLostCancelsTick(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	locker := &stealingLocker{}
+	cause := make(chan error, 1)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithFireOnStart(),
+		tickermod.WithLocker(locker, "job"),
+		tickermod.WithLogger(discardLogger),
+		tickermod.WithFuncCtx(func(ctx context.Context) error {
+			<-ctx.Done()
+			cause <- context.Cause(ctx)
+			return ctx.Err()
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	locker.steal.Store(true)
+	clock.Advance(30 * time.Second)
+	require.ErrorIs(t, <-cause, tickermod.ErrLeaseLost)
+	require.ErrorIs(t, wg.Wait(), tickermod.ErrLeaseLost, "lost lease is a failure")
+	require.ErrorIs(t, tickerMod.Status().LastError, tickermod.ErrLeaseLost)
+	require.NoError(t, tickerMod.Stop())
+}
+
+func TestLockerError(t *testing.T) {
+	errLock := errors.New("db down")
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithLocker(lockerFunc(func() (bool, error) { return false, errLock }), "job"),
+		tickermod.WithFunc(func() error { return nil }),
+	)
+	require.NoError(t, tickerMod.Init())
+	err := tickerMod.Run()
+	require.ErrorIs(t, err, tickermod.ErrLockFailed)
+	require.ErrorIs(t, err, errLock)
+	require.NoError(t, tickerMod.Stop())
+}
+
+func TestLockerMissingKey(t *testing.T) {
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithLocker(tickermodtest.NewLocker(time.Now), ""),
+		tickermod.WithFunc(func() error { return nil }),
+	)
+	require.ErrorIs(t, tickerMod.Init(), tickermod.ErrMissingLockKey)
+}
+
+// renewLocker reports every TryLock call once it has completed.
+type renewLocker struct {
+	*tickermodtest.Locker
+	calls chan struct{}
+}
+
+func (l *renewLocker) TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	defer func() {
+		select {
+		case l.calls <- struct{}{}:
+		default:
+		}
+	}()
+	return l.Locker.TryLock(ctx, key, owner, ttl)
+}
+
+type lockerFunc func() (bool, error)
+
+func (f lockerFunc) TryLock(context.Context, string, string, time.Duration) (bool, error) {
+	return f()
+}
+
+// stealingLocker grants the first lease and refuses renewals once steal is set.
+type stealingLocker struct{ steal atomic.Bool }
+
+func (l *stealingLocker) TryLock(context.Context, string, string, time.Duration) (bool, error) {
+	return !l.steal.Load(), nil
+}
@@ -0,0 +1,174 @@
+package tickermod_test
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlapSkip(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithFunc(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	clock.Advance(3*time.Minute + 30*time.Second)
+	release <- struct{}{}
+
+	clock.BlockUntil(1)
+	require.EqualValues(t, 3, tickerMod.Missed())
+	clock.Advance(30 * time.Second)
+	<-started
+	release <- struct{}{}
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestOverlapSkipCron(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithLocation(time.UTC),
+		tickermod.WithSchedule("0 * * * * *"),
+		tickermod.WithFunc(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	clock.Advance(2 * time.Minute)
+	release <- struct{}{}
+
+	// 00:02 was missed, 00:03 is due right now.
+	<-started
+	require.EqualValues(t, 1, tickerMod.Missed())
+	release <- struct{}{}
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestOverlapQueue(t *testing.T) {
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithOverlap(tickermod.OverlapQueue()),
+		tickermod.WithFunc(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+	clock.Advance(3*time.Minute + 30*time.Second)
+	release <- struct{}{}
+
+	// Activations at 2, 3 and 4 minutes run back to back without advancing.
+	for range 3 {
+		<-started
+		release <- struct{}{}
+	}
+	require.Zero(t, tickerMod.Missed())
+
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+}
+
+func TestOverlapConcurrent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		started := make(chan struct{}, 3)
+		release := make(chan struct{})
+		tickerMod := tickermod.New(
+			tickermod.WithClock(clock),
+			tickermod.WithInterval(time.Minute),
+			tickermod.WithOverlap(tickermod.OverlapConcurrent(2)),
+			tickermod.WithFuncCtx(func(ctx context.Context) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		for range 3 {
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			synctest.Wait()
+		}
+		require.Len(t, started, 2)
+		require.EqualValues(t, 1, tickerMod.Missed())
+
+		stopped := make(chan error, 1)
+		go func() { stopped <- tickerMod.Stop() }()
+		synctest.Wait()
+		require.Empty(t, stopped, "Stop returned before in-flight ticks finished")
+
+		close(release)
+		require.NoError(t, <-stopped)
+		require.NoError(t, wg.Wait())
+	})
+}
+
+func TestOverlapConcurrentError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(time.Second),
+			tickermod.WithOverlap(tickermod.OverlapConcurrent(4)),
+			tickermod.WithFunc(func() error { return errTick }),
+		)
+		require.NoError(t, tickerMod.Init())
+		require.ErrorIs(t, tickerMod.Run(), errTick)
+		require.NoError(t, tickerMod.Stop())
+	})
+}
+
+func TestOverlapInvalid(t *testing.T) {
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Second),
+		tickermod.WithOverlap(tickermod.OverlapConcurrent(0)),
+		tickermod.WithFunc(func() error { return nil }),
+	)
+	require.ErrorIs(t, tickerMod.Init(), tickermod.ErrInvalidOverlap)
+}
@@ -0,0 +1,147 @@
+package tickermod
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+type errorMode int
+
+const (
+	modeStop errorMode = iota
+	modeContinue
+	modeRetry
+)
+
+// ErrorPolicy decides what happens when the tick function returns an error.
+type ErrorPolicy struct {
+	mode       errorMode
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// StopOnError makes Run return the tick error, which shuts down srvc.
+// This is the default policy.
+func StopOnError() ErrorPolicy { return ErrorPolicy{mode: modeStop} }
+
+// ContinueOnError logs the tick error and waits for the next tick.
+func ContinueOnError() ErrorPolicy { return ErrorPolicy{mode: modeContinue} }
+
+// RetryOnError calls the tick function up to attempts times in total,
+// waiting backoff before the first retry and doubling the wait after each
+// one, up to an hour or backoff if it's longer. Run returns the last error
+// if every attempt fails.
+func RetryOnError(attempts int, backoff time.Duration) ErrorPolicy {
+	return RetryOnErrorUpTo(attempts, backoff, max(backoff, time.Hour))
+}
+
+// RetryOnErrorUpTo is RetryOnError with the wait doubled up to maxDelay.
+func RetryOnErrorUpTo(attempts int, backoff, maxDelay time.Duration) ErrorPolicy {
+	return ErrorPolicy{mode: modeRetry, attempts: attempts, backoff: backoff, maxBackoff: maxDelay}
+}
+
+func (p ErrorPolicy) validate() error {
+	if p.mode == modeRetry && (p.attempts < 1 || p.backoff < 0 || p.maxBackoff < p.backoff) {
+		return ErrInvalidPolicy
+	}
+	return nil
+}
+
+// delay returns the wait before retrying after attempt failed.
+func (p ErrorPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt && d > 0 && d < p.maxBackoff; i++ {
+		if d > p.maxBackoff/2 {
+			return p.maxBackoff
+		}
+		d *= 2
+	}
+	return d
+}
+
+// handle applies the policy to err returned by attempt. It returns the
+// delay before the next attempt and whether to retry at all. A nil error
+// returned alongside retry=false means the error was swallowed.
+func (p ErrorPolicy) handle(log *slog.Logger, id string, attempt int, err error) (time.Duration, bool, error) {
+	switch p.mode {
+	case modeContinue:
+		log.Error("Tick failed", slog.String("id", id), slog.Any("error", err))
+		return 0, false, nil
+	case modeRetry:
+		if attempt >= p.attempts {
+			return 0, false, err
+		}
+		delay := p.delay(attempt)
+		log.Warn("Tick failed, retrying", slog.String("id", id), slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("error", err))
+		return delay, true, nil
+	default:
+		return 0, false, err
+	}
+}
+
+type overlapMode int
+
+const (
+	overlapSkip overlapMode = iota
+	overlapQueue
+	overlapConcurrent
+)
+
+// OverlapPolicy decides what happens to activations that are due while
+// previous ticks are still running.
+type OverlapPolicy struct {
+	mode  overlapMode
+	limit int
+}
+
+// OverlapSkip runs ticks one at a time and skips activations missed while
+// a tick was running. Skipped activations are counted by Ticker.Missed.
+// This is the default policy.
+func OverlapSkip() OverlapPolicy { return OverlapPolicy{mode: overlapSkip} }
+
+// OverlapQueue runs ticks one at a time and runs every missed activation
+// back to back once the slow tick returns.
+func OverlapQueue() OverlapPolicy { return OverlapPolicy{mode: overlapQueue} }
+
+// OverlapConcurrent runs each tick in its own goroutine with up to limit
+// ticks in flight. Activations due while all slots are taken are skipped
+// and counted by Ticker.Missed. Each tick gets its own WithTickTimeout.
+func OverlapConcurrent(limit int) OverlapPolicy {
+	return OverlapPolicy{mode: overlapConcurrent, limit: limit}
+}
+
+type catchUpMode int
+
+const (
+	catchUpOnce catchUpMode = iota
+	catchUpSkip
+	catchUpAll
+)
+
+// CatchUpPolicy decides what happens to activations missed while the
+// service was down, based on the last run loaded from the state store.
+type CatchUpPolicy struct {
+	mode  catchUpMode
+	limit int
+}
+
+// CatchUpOnce runs the tick once on Run if any activation was missed, and
+// continues with the schedule after it. This is the default policy.
+func CatchUpOnce() CatchUpPolicy { return CatchUpPolicy{mode: catchUpOnce} }
+
+// CatchUpSkip skips missed activations and waits for the next one.
+func CatchUpSkip() CatchUpPolicy { return CatchUpPolicy{mode: catchUpSkip} }
+
+// CatchUpAll runs every missed activation back to back on Run, oldest
+// first, up to limit runs. Activations beyond the limit are skipped.
+func CatchUpAll(limit int) CatchUpPolicy { return CatchUpPolicy{mode: catchUpAll, limit: limit} }
+
+// jitter returns random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
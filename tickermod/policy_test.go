@@ -0,0 +1,187 @@
+package tickermod_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errTick       = errors.New("tick error")
+	discardLogger = slog.New(slog.DiscardHandler)
+)
+
+func TestContinueOnError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		calls := make(chan struct{}, 3)
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(time.Second),
+			tickermod.WithErrorPolicy(tickermod.ContinueOnError()),
+			tickermod.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+			tickermod.WithFunc(func() error {
+				calls <- struct{}{}
+				return errors.New("tick error")
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		for range 3 {
+			<-calls
+		}
+		require.NoError(t, tickerMod.Stop())
+		require.NoError(t, wg.Wait())
+		require.Contains(t, logs.String(), "tick error")
+	})
+}
+
+func TestRetryOnErrorSucceeds(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls []time.Time
+		done := make(chan struct{})
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(time.Hour),
+			tickermod.WithFireOnStart(),
+			tickermod.WithErrorPolicy(tickermod.RetryOnError(3, time.Second)),
+			tickermod.WithLogger(discardLogger),
+			tickermod.WithFunc(func() error {
+				calls = append(calls, time.Now())
+				if len(calls) < 3 {
+					return errors.New("flaky")
+				}
+				close(done)
+				return nil
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		<-done
+		require.Len(t, calls, 3)
+		require.Equal(t, time.Second, calls[1].Sub(calls[0]))
+		require.Equal(t, 2*time.Second, calls[2].Sub(calls[1]))
+
+		require.NoError(t, tickerMod.Stop())
+		require.NoError(t, wg.Wait())
+	})
+}
+
+func TestRetryOnErrorExhausted(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		calls := atomic.Int32{}
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(time.Hour),
+			tickermod.WithFireOnStart(),
+			tickermod.WithErrorPolicy(tickermod.RetryOnError(4, time.Millisecond)),
+			tickermod.WithLogger(discardLogger),
+			tickermod.WithFunc(func() error {
+				calls.Add(1)
+				return errTick
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		require.ErrorIs(t, tickerMod.Run(), errTick)
+		require.EqualValues(t, 4, calls.Load())
+		require.NoError(t, tickerMod.Stop())
+	})
+}
+
+func TestRetryOnErrorDelay(t *testing.T) {
+	p := tickermod.RetryOnErrorUpTo(100, time.Second, 5*time.Second)
+	require.Equal(t, time.Second, tickermod.RetryDelay(p, 1))
+	require.Equal(t, 4*time.Second, tickermod.RetryDelay(p, 3))
+	require.Equal(t, 5*time.Second, tickermod.RetryDelay(p, 4))
+	require.Equal(t, 5*time.Second, tickermod.RetryDelay(p, 99))
+
+	p = tickermod.RetryOnError(1000, time.Second)
+	require.Equal(t, time.Hour, tickermod.RetryDelay(p, 999), "doesn't overflow")
+	p = tickermod.RetryOnError(1000, 0)
+	require.Zero(t, tickermod.RetryDelay(p, 999))
+}
+
+func TestRetryOnErrorStopDuringBackoff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		failed := make(chan struct{}, 1)
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(time.Hour),
+			tickermod.WithFireOnStart(),
+			tickermod.WithErrorPolicy(tickermod.RetryOnError(2, time.Hour)),
+			tickermod.WithLogger(discardLogger),
+			tickermod.WithFuncCtx(func(ctx context.Context) error {
+				failed <- struct{}{}
+				return errors.New("tick error")
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		<-failed
+		synctest.Wait()
+		require.NoError(t, tickerMod.Stop())
+		require.NoError(t, wg.Wait())
+	})
+}
+
+func TestJitter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const interval, jitter = time.Minute, 10 * time.Second
+		start := time.Now()
+		ticks := make(chan time.Time, 1)
+		tickerMod := tickermod.New(
+			tickermod.WithInterval(interval),
+			tickermod.WithJitter(jitter),
+			tickermod.WithFunc(func() error {
+				ticks <- time.Now()
+				return nil
+			}),
+		)
+		require.NoError(t, tickerMod.Init())
+		wg := &errgroup.ErrGroup{}
+		wg.Go(tickerMod.Run)
+
+		for i := 1; i <= 10; i++ {
+			offset := (<-ticks).Sub(start) - time.Duration(i)*interval
+			require.GreaterOrEqual(t, offset, time.Duration(0))
+			require.Less(t, offset, jitter)
+		}
+
+		require.NoError(t, tickerMod.Stop())
+		require.NoError(t, wg.Wait())
+	})
+}
+
+func TestPolicyInitErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		opt         tickermod.Opt
+		expectedErr error
+	}{
+		{"RetryZeroAttempts", tickermod.WithErrorPolicy(tickermod.RetryOnError(0, time.Second)), tickermod.ErrInvalidPolicy},
+		{"RetryNegativeBackoff", tickermod.WithErrorPolicy(tickermod.RetryOnError(1, -time.Second)), tickermod.ErrInvalidPolicy},
+		{"RetryMaxBelowBackoff", tickermod.WithErrorPolicy(tickermod.RetryOnErrorUpTo(1, time.Second, time.Millisecond)), tickermod.ErrInvalidPolicy},
+		{"NegativeJitter", tickermod.WithJitter(-time.Second), tickermod.ErrInvalidJitter},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tickerMod := tickermod.New(
+				tickermod.WithInterval(time.Second),
+				tickermod.WithFunc(func() error { return nil }),
+				tc.opt,
+			)
+			require.ErrorIs(t, tickerMod.Init(), tc.expectedErr)
+		})
+	}
+}
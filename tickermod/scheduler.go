@@ -0,0 +1,209 @@
+package tickermod
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const SchedulerID = "scheduler"
+
+const (
+	ErrMissingJobName   = errStr("job name not set")
+	ErrDuplicateJob     = errStr("job already exists")
+	ErrJobNotFound      = errStr("job not found")
+	ErrSchedulerStopped = errStr("scheduler stopped")
+)
+
+// Scheduler hosts many named jobs under a single module lifecycle. Each job
+// is a Ticker configured with its own options, so intervals, schedules,
+// timeouts, fire-on-start and error policies are set per job. Jobs can be
+// added and removed while the scheduler runs.
+//
+// A job whose ticker stops with an error stops the whole scheduler, so use
+// ContinueOnError or RetryOnError for jobs that should not.
+type Scheduler struct {
+	opts     []SchedulerOpt
+	defaults []Opt
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	running bool
+	stopped bool
+	errs    chan error
+	wg      sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type job struct {
+	ticker *Ticker
+	done   chan struct{}
+}
+
+// NewScheduler creates scheduler with given options.
+func NewScheduler(opts ...SchedulerOpt) *Scheduler {
+	return &Scheduler{opts: opts}
+}
+
+func (s *Scheduler) Init() error {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.errs = make(chan error, 1)
+	s.jobs = map[string]*job{}
+	for _, opt := range s.opts {
+		if err := opt(s); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) Run() error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.running = true
+		for _, j := range s.jobs {
+			s.start(j)
+		}
+	}
+	s.mu.Unlock()
+
+	select {
+	case err := <-s.errs:
+		return err
+	case <-s.ctx.Done():
+		return nil
+	}
+}
+
+// Stop stops all jobs and waits for them to return.
+func (s *Scheduler) Stop() error {
+	s.mu.Lock()
+	s.stopped = true
+	jobs := s.jobs
+	s.jobs = map[string]*job{}
+	s.mu.Unlock()
+
+	s.cancel()
+	for _, j := range jobs {
+		_ = j.ticker.Stop()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) ID() string { return SchedulerID }
+
+// Add creates a job from given ticker options and starts it if the
+// scheduler is already running. The name is set with WithName and must be
+// unique. Options set by WithDefaults are applied first. Add can be called
+// once the scheduler is initialized.
+func (s *Scheduler) Add(name string, opts ...Opt) error {
+	if name == "" {
+		return ErrMissingJobName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateJob, name)
+	}
+
+	opts = append(slices.Clone(s.defaults), opts...)
+	t := New(append(opts, WithName(name))...)
+	if err := t.Init(); err != nil {
+		return fmt.Errorf("job %q: %w", name, err)
+	}
+	j := &job{ticker: t, done: make(chan struct{})}
+	s.jobs[name] = j
+	if s.running {
+		s.start(j)
+	}
+	return nil
+}
+
+// Remove stops the named job and waits for it to return.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	delete(s.jobs, name)
+	running := s.running
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrJobNotFound, name)
+	}
+
+	err := j.ticker.Stop()
+	if running {
+		<-j.done
+	}
+	return err
+}
+
+// Status returns the status of the named job.
+func (s *Scheduler) Status(name string) (Status, error) {
+	t, err := s.ticker(name)
+	if err != nil {
+		return Status{}, err
+	}
+	return t.Status(), nil
+}
+
+// Statuses returns the status of every job sorted by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.ticker.Status())
+	}
+	s.mu.Unlock()
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+func (s *Scheduler) ticker(name string) (*Ticker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrJobNotFound, name)
+	}
+	return j.ticker, nil
+}
+
+// start runs the job until it returns. Must be called with s.mu held.
+func (s *Scheduler) start(j *job) {
+	s.wg.Go(func() {
+		defer close(j.done)
+		if err := j.ticker.Run(); err != nil {
+			select {
+			case s.errs <- fmt.Errorf("job %q: %w", j.ticker.name, err):
+			default:
+			}
+		}
+	})
+}
+
+type SchedulerOpt func(*Scheduler) error
+
+// WithJob adds a named job during Init, see Scheduler.Add.
+func WithJob(name string, opts ...Opt) SchedulerOpt {
+	return func(s *Scheduler) error {
+		return s.Add(name, opts...)
+	}
+}
+
+// WithDefaults sets ticker options applied to every job before its own
+// options, for example WithLogger, WithClock or WithMetrics. Only jobs
+// added after this option are affected.
+func WithDefaults(opts ...Opt) SchedulerOpt {
+	return func(s *Scheduler) error {
+		s.defaults = append(s.defaults, opts...)
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package tickermod_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := tickermodtest.NewClock(start)
+	calls := make(chan string, 10)
+	record := func(name string, err error) tickermod.Opt {
+		return tickermod.WithFunc(func() error {
+			calls <- name
+			return err
+		})
+	}
+
+	s := tickermod.NewScheduler(
+		tickermod.WithDefaults(
+			tickermod.WithClock(clock),
+			tickermod.WithLocation(time.UTC),
+			tickermod.WithLogger(discardLogger),
+			tickermod.WithErrorPolicy(tickermod.ContinueOnError()),
+		),
+		tickermod.WithJob("fast", tickermod.WithInterval(time.Minute), record("fast", nil)),
+		tickermod.WithJob("slow", tickermod.WithInterval(time.Hour), record("slow", errTick)),
+	)
+	require.NoError(t, s.Init())
+	require.Equal(t, "scheduler", s.ID())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(s.Run)
+
+	clock.BlockUntil(2)
+	status, err := s.Status("fast")
+	require.NoError(t, err)
+	require.Equal(t, tickermod.Status{Name: "fast", NextRun: start.Add(time.Minute)}, status)
+
+	clock.Advance(time.Minute)
+	require.Equal(t, "fast", <-calls)
+	clock.BlockUntil(2)
+
+	require.NoError(t, s.Add("added", tickermod.WithInterval(40*time.Second), tickermod.WithFireOnStart(), record("added", nil)))
+	require.Equal(t, "added", <-calls)
+	require.ErrorIs(t, s.Add("added", tickermod.WithInterval(time.Second)), tickermod.ErrDuplicateJob)
+	clock.BlockUntil(3)
+
+	require.NoError(t, s.Remove("fast"))
+	require.ErrorIs(t, s.Remove("fast"), tickermod.ErrJobNotFound)
+	_, err = s.Status("fast")
+	require.ErrorIs(t, err, tickermod.ErrJobNotFound)
+
+	clock.Advance(59 * time.Minute)
+	got := []string{<-calls, <-calls}
+	require.ElementsMatch(t, []string{"added", "slow"}, got)
+	clock.BlockUntil(2)
+
+	statuses := s.Statuses()
+	require.Len(t, statuses, 2)
+	require.Equal(t, "added", statuses[0].Name)
+	require.NoError(t, statuses[0].LastError)
+	require.Equal(t, "slow", statuses[1].Name)
+	require.Equal(t, start.Add(time.Hour), statuses[1].LastRun)
+	require.Equal(t, start.Add(2*time.Hour), statuses[1].NextRun)
+	require.ErrorIs(t, statuses[1].LastError, errTick)
+	require.Empty(t, calls)
+
+	require.NoError(t, s.Stop())
+	require.NoError(t, wg.Wait())
+	require.ErrorIs(t, s.Add("late", tickermod.WithInterval(time.Second)), tickermod.ErrSchedulerStopped)
+}
+
+func TestSchedulerJobError(t *testing.T) {
+	s := tickermod.NewScheduler(
+		tickermod.WithJob("ok", tickermod.WithInterval(time.Hour), tickermod.WithFunc(func() error { return nil })),
+		tickermod.WithJob("broken",
+			tickermod.WithInterval(time.Hour),
+			tickermod.WithFireOnStart(),
+			tickermod.WithFunc(func() error { return errTick }),
+		),
+	)
+	require.NoError(t, s.Init())
+	err := s.Run()
+	require.ErrorIs(t, err, errTick)
+	require.ErrorContains(t, err, `job "broken"`)
+	require.NoError(t, s.Stop())
+}
+
+func TestSchedulerInvalidJob(t *testing.T) {
+	s := tickermod.NewScheduler(tickermod.WithJob("", tickermod.WithInterval(time.Hour)))
+	require.ErrorIs(t, s.Init(), tickermod.ErrMissingJobName)
+
+	s = tickermod.NewScheduler(tickermod.WithJob("job", tickermod.WithFunc(func() error { return nil })))
+	require.ErrorIs(t, s.Init(), tickermod.ErrMissingInterval)
+}
+
+func TestSchedulerStopBeforeRun(t *testing.T) {
+	s := tickermod.NewScheduler(tickermod.WithJob("job",
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithFunc(func() error { panic("must not run") }),
+	))
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Stop())
+	require.NoError(t, s.Run())
+}
+
+func TestTickerID(t *testing.T) {
+	tickerMod := tickermod.New(tickermod.WithName("cleanup"), tickermod.WithInterval(time.Hour), tickermod.WithFunc(func() error { return nil }))
+	require.Equal(t, "tickermod", tickerMod.ID())
+	require.NoError(t, tickerMod.Init())
+	require.Equal(t, "tickermod/cleanup", tickerMod.ID())
+}
@@ -0,0 +1,166 @@
+package tickermod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateStore persists the time of the last successful tick so that
+// schedules continue where they left off after a restart. See FileStore
+// and tickersql for implementations.
+type StateStore interface {
+	// LastRun returns the last run stored for key, or zero time if none.
+	LastRun(ctx context.Context, key string) (time.Time, error)
+	// SetLastRun stores the last run for key.
+	SetLastRun(ctx context.Context, key string, t time.Time) error
+}
+
+// restore loads the last run from the state store and catches up on the
+// activations missed since then. It returns the activation to continue the
+// schedule from, or zero time when there is no state to resume from.
+func (t *Ticker) restore() (time.Time, error) {
+	if t.state == nil {
+		return time.Time{}, nil
+	}
+	last, err := t.state.LastRun(t.ctx, t.stateKey)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrStateFailed, err)
+	}
+	if last.IsZero() {
+		return last, nil
+	}
+
+	now := t.clock.Now()
+	switch {
+	case t.paused.Load():
+	case t.catchUp.mode == catchUpAll:
+		for range t.catchUp.limit {
+			next := t.schedule().next(last.In(t.loc))
+			if next.IsZero() || next.After(now) {
+				return last, nil
+			}
+			if err := t.fire(next); err != nil {
+				return time.Time{}, err
+			}
+			last = next
+		}
+	case t.catchUp.mode == catchUpOnce:
+		if missed := t.latest(last, now); !missed.IsZero() {
+			return missed, t.fire(missed)
+		}
+	}
+	if missed := t.latest(last, now); !missed.IsZero() {
+		return missed, nil
+	}
+	return last, nil
+}
+
+// latest returns the last activation after from that is not after now,
+// or zero time if there is none.
+func (t *Ticker) latest(from, now time.Time) time.Time {
+	sched := t.schedule()
+	if iv, ok := sched.(interval); ok {
+		n := now.Sub(from) / time.Duration(iv)
+		if n < 1 {
+			return time.Time{}
+		}
+		return from.Add(n * time.Duration(iv))
+	}
+	var latest time.Time
+	for next := sched.next(from.In(t.loc)); !next.IsZero() && !next.After(now); next = sched.next(next) {
+		latest = next
+	}
+	return latest
+}
+
+// saveState stores at as the last run. Failures are logged since the tick
+// itself succeeded.
+func (t *Ticker) saveState(ctx context.Context, at time.Time) {
+	if t.state == nil {
+		return
+	}
+	if err := t.state.SetLastRun(context.WithoutCancel(ctx), t.stateKey, at); err != nil {
+		t.log.Warn("Failed to save tick state", slog.String("id", t.name), slog.String("key", t.stateKey), slog.Any("error", err))
+	}
+}
+
+var _ StateStore = (*FileStore)(nil)
+
+// FileStore keeps last runs of all keys in a single JSON file. The file is
+// replaced atomically on every update.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates FileStore using the file at path. The file and its
+// directory are created on first update.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// LastRun implements StateStore.
+func (f *FileStore) LastRun(_ context.Context, key string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state, err := f.read()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state[key], nil
+}
+
+// SetLastRun implements StateStore.
+func (f *FileStore) SetLastRun(_ context.Context, key string, t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state, err := f.read()
+	if err != nil {
+		return err
+	}
+	state[key] = t
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint: errcheck
+	if _, err := tmp.Write(data); err != nil {
+		return errors.Join(err, tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *FileStore) read() (map[string]time.Time, error) {
+	state := map[string]time.Time{}
+	data, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return state, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", f.path, err)
+	}
+	return state, nil
+}
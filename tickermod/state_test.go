@@ -0,0 +1,208 @@
+package tickermod_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "ticks.json")
+	s := tickermod.NewFileStore(path)
+
+	last, err := s.LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, last.IsZero())
+
+	now := time.Now()
+	require.NoError(t, s.SetLastRun(ctx, "job", now))
+	require.NoError(t, s.SetLastRun(ctx, "other", now.Add(time.Hour)))
+
+	last, err = tickermod.NewFileStore(path).LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, now.Equal(last))
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = s.LastRun(ctx, "job")
+	require.Error(t, err)
+}
+
+func TestStateRestore(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name    string
+		stored  time.Time
+		catchUp tickermod.CatchUpPolicy
+		// Activations expected to run on start and the next one after them.
+		runs []time.Time
+		next time.Time
+	}{
+		{
+			name: "NoState",
+			runs: []time.Time{t0.Add(5*time.Minute + 30*time.Second)},
+			next: t0.Add(6*time.Minute + 30*time.Second),
+		},
+		{
+			name:    "NothingMissed",
+			stored:  t0.Add(5 * time.Minute),
+			catchUp: tickermod.CatchUpOnce(),
+			next:    t0.Add(6 * time.Minute),
+		},
+		{
+			name:    "Once",
+			stored:  t0,
+			catchUp: tickermod.CatchUpOnce(),
+			runs:    []time.Time{t0.Add(5 * time.Minute)},
+			next:    t0.Add(6 * time.Minute),
+		},
+		{
+			name:    "Skip",
+			stored:  t0,
+			catchUp: tickermod.CatchUpSkip(),
+			next:    t0.Add(6 * time.Minute),
+		},
+		{
+			name:    "All",
+			stored:  t0.Add(2 * time.Minute),
+			catchUp: tickermod.CatchUpAll(10),
+			runs:    []time.Time{t0.Add(3 * time.Minute), t0.Add(4 * time.Minute), t0.Add(5 * time.Minute)},
+			next:    t0.Add(6 * time.Minute),
+		},
+		{
+			name:    "AllLimit",
+			stored:  t0,
+			catchUp: tickermod.CatchUpAll(2),
+			runs:    []time.Time{t0.Add(time.Minute), t0.Add(2 * time.Minute)},
+			next:    t0.Add(6 * time.Minute),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := tickermodtest.NewClock(t0.Add(5*time.Minute + 30*time.Second))
+			store := &memStore{}
+			if !tt.stored.IsZero() {
+				require.NoError(t, store.SetLastRun(ctx, "job", tt.stored))
+				store.saved = nil
+			}
+
+			tickerMod := tickermod.New(
+				tickermod.WithClock(clock),
+				tickermod.WithLocation(time.UTC),
+				tickermod.WithInterval(time.Minute),
+				tickermod.WithFireOnStart(),
+				tickermod.WithStateStore(store, "job"),
+				tickermod.WithCatchUp(tt.catchUp),
+				tickermod.WithFunc(func() error { return nil }),
+			)
+			require.NoError(t, tickerMod.Init())
+			wg := &errgroup.ErrGroup{}
+			wg.Go(tickerMod.Run)
+
+			clock.BlockUntil(1)
+			require.Equal(t, tt.runs, store.savedRuns())
+			require.Equal(t, tt.next, tickerMod.Status().NextRun)
+			require.Zero(t, tickerMod.Missed())
+
+			clock.Advance(tt.next.Sub(clock.Now()))
+			clock.BlockUntil(1)
+			require.Equal(t, append(tt.runs, tt.next), store.savedRuns())
+
+			require.NoError(t, tickerMod.Stop())
+			require.NoError(t, wg.Wait())
+		})
+	}
+}
+
+func TestStateNotSavedOnError(t *testing.T) {
+	store := &memStore{}
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithStateStore(store, "job"),
+		tickermod.WithFunc(func() error { return errTick }),
+	)
+	require.NoError(t, tickerMod.Init())
+	require.ErrorIs(t, tickerMod.Run(), errTick)
+	require.NoError(t, tickerMod.Stop())
+	require.Empty(t, store.savedRuns())
+}
+
+func TestStateNotSavedOnCancel(t *testing.T) {
+	store := &memStore{}
+	started := make(chan struct{}, 1)
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithStateStore(store, "job"),
+		tickermod.WithFuncCtx(func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	require.ErrorIs(t, tickerMod.Trigger(ctx), context.Canceled)
+
+	wg := &errgroup.ErrGroup{}
+	wg.Go(func() error { return tickerMod.Trigger(context.Background()) })
+	<-started
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+	require.Empty(t, store.savedRuns(), "canceled ticks are not completed runs")
+}
+
+func TestStateOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opt  tickermod.Opt
+		err  error
+	}{
+		{name: "MissingKey", opt: tickermod.WithStateStore(&memStore{}, ""), err: tickermod.ErrMissingStateKey},
+		{name: "InvalidCatchUp", opt: tickermod.WithCatchUp(tickermod.CatchUpAll(0)), err: tickermod.ErrInvalidCatchUp},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tickerMod := tickermod.New(tickermod.WithInterval(time.Hour), tickermod.WithFunc(func() error { return nil }), tt.opt)
+			require.ErrorIs(t, tickerMod.Init(), tt.err)
+		})
+	}
+}
+
+type memStore struct {
+	mu    sync.Mutex
+	last  time.Time
+	saved []time.Time
+}
+
+func (m *memStore) LastRun(context.Context, string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last, nil
+}
+
+func (m *memStore) SetLastRun(_ context.Context, _ string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = t
+	m.saved = append(m.saved, t)
+	return nil
+}
+
+func (m *memStore) savedRuns() []time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saved
+}
@@ -0,0 +1,117 @@
+package tickermod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/go-srvc/mods/tickermod"
+
+// telemetry records tick executions. A nil *telemetry records nothing.
+type telemetry struct {
+	name        string
+	attrs       metric.MeasurementOption
+	tracer      trace.Tracer
+	metrics     bool
+	duration    metric.Float64Histogram
+	succeeded   metric.Int64Counter
+	failed      metric.Int64Counter
+	missed      metric.Int64Counter
+	lastSuccess metric.Float64Gauge
+}
+
+func newTelemetry(name string, tracing, metrics bool) (*telemetry, error) {
+	if !tracing && !metrics {
+		return nil, nil
+	}
+	tel := &telemetry{
+		name:    name,
+		attrs:   metric.WithAttributeSet(attribute.NewSet(attribute.String("job.name", name))),
+		metrics: metrics,
+	}
+	if tracing {
+		tel.tracer = otel.Tracer(instrumentationName)
+	}
+	if !metrics {
+		return tel, nil
+	}
+
+	m := otel.Meter(instrumentationName)
+	var errs [5]error
+	tel.duration, errs[0] = m.Float64Histogram("tickermod.tick.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of tick function calls."),
+	)
+	tel.succeeded, errs[1] = m.Int64Counter("tickermod.tick.succeeded",
+		metric.WithDescription("Number of tick function calls that returned no error."),
+	)
+	tel.failed, errs[2] = m.Int64Counter("tickermod.tick.failed",
+		metric.WithDescription("Number of tick function calls that returned an error."),
+	)
+	tel.missed, errs[3] = m.Int64Counter("tickermod.tick.missed",
+		metric.WithDescription("Number of activations skipped because previous ticks were still running."),
+	)
+	tel.lastSuccess, errs[4] = m.Float64Gauge("tickermod.tick.last_success",
+		metric.WithUnit("s"),
+		metric.WithDescription("Unix time of the last successful tick function call."),
+	)
+	if err := errors.Join(errs[:]...); err != nil {
+		return nil, fmt.Errorf("failed to create instruments: %w", err)
+	}
+	return tel, nil
+}
+
+// start starts a span for a single tick function call.
+func (tel *telemetry) start(ctx context.Context, attempt int) (context.Context, trace.Span) {
+	if tel == nil || tel.tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tel.tracer.Start(ctx, "tick "+tel.name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("job.name", tel.name),
+			attribute.Int("tickermod.attempt", attempt),
+		),
+	)
+}
+
+// end finishes the span and records the outcome of a tick function call.
+// Calls canceled by Stop count neither as success nor failure.
+func (tel *telemetry) end(ctx context.Context, span trace.Span, start, now time.Time, err error) {
+	if tel == nil {
+		return
+	}
+	if tel.tracer != nil {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+	if !tel.metrics || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	tel.duration.Record(ctx, now.Sub(start).Seconds(), tel.attrs)
+	if err != nil {
+		tel.failed.Add(ctx, 1, tel.attrs)
+		return
+	}
+	tel.succeeded.Add(ctx, 1, tel.attrs)
+	tel.lastSuccess.Record(ctx, float64(now.UnixNano())/1e9, tel.attrs)
+}
+
+func (tel *telemetry) addMissed(ctx context.Context, n uint64) {
+	if tel == nil || !tel.metrics {
+		return
+	}
+	tel.missed.Add(ctx, int64(n), tel.attrs)
+}
@@ -0,0 +1,115 @@
+package tickermod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/heppu/errgroup"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	results := make(chan error)
+	tickerMod := tickermod.New(
+		tickermod.WithClock(clock),
+		tickermod.WithInterval(time.Minute),
+		tickermod.WithName("cleanup"),
+		tickermod.WithTracing(),
+		tickermod.WithMetrics(),
+		tickermod.WithErrorPolicy(tickermod.ContinueOnError()),
+		tickermod.WithLogger(discardLogger),
+		tickermod.WithFunc(func() error {
+			clock.Advance(2 * time.Second)
+			return <-results
+		}),
+	)
+	require.NoError(t, tickerMod.Init())
+	wg := &errgroup.ErrGroup{}
+	wg.Go(tickerMod.Run)
+
+	for _, err := range []error{nil, errTick, nil} {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		results <- err
+	}
+	clock.BlockUntil(1)
+	// Fire the next activation a minute late so that the one after it passes
+	// while the tick is still running.
+	clock.Advance(2 * time.Minute)
+	results <- nil
+	clock.BlockUntil(1)
+	require.NoError(t, tickerMod.Stop())
+	require.NoError(t, wg.Wait())
+
+	ended := spans.Ended()
+	require.Len(t, ended, 4)
+	require.Equal(t, "tick cleanup", ended[0].Name())
+	require.Contains(t, ended[0].Attributes(), attribute.String("job.name", "cleanup"))
+	require.Equal(t, codes.Unset, ended[0].Status().Code)
+	require.Equal(t, codes.Error, ended[1].Status().Code)
+	require.Equal(t, errTick.Error(), ended[1].Status().Description)
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	got := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m.Data
+	}
+	job := attribute.NewSet(attribute.String("job.name", "cleanup"))
+
+	duration := got["tickermod.tick.duration"].(metricdata.Histogram[float64])
+	require.Equal(t, job, duration.DataPoints[0].Attributes)
+	require.Equal(t, uint64(4), duration.DataPoints[0].Count)
+	require.Equal(t, 8.0, duration.DataPoints[0].Sum)
+
+	for name, want := range map[string]int64{
+		"tickermod.tick.succeeded": 3,
+		"tickermod.tick.failed":    1,
+		"tickermod.tick.missed":    1,
+	} {
+		sum := got[name].(metricdata.Sum[int64])
+		require.Equal(t, job, sum.DataPoints[0].Attributes, name)
+		require.Equal(t, want, sum.DataPoints[0].Value, name)
+	}
+
+	last := got["tickermod.tick.last_success"].(metricdata.Gauge[float64])
+	require.Equal(t, float64(clock.Now().Unix()), last.DataPoints[0].Value)
+}
+
+func TestTelemetryDisabled(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+
+	tickerMod := tickermod.New(
+		tickermod.WithInterval(time.Hour),
+		tickermod.WithFireOnStart(),
+		tickermod.WithFunc(func() error { return errTick }),
+	)
+	require.NoError(t, tickerMod.Init())
+	require.ErrorIs(t, tickerMod.Run(), errTick)
+	require.NoError(t, tickerMod.Stop())
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Empty(t, rm.ScopeMetrics)
+	require.Empty(t, spans.Ended())
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,7 +16,22 @@ const ID = "tickermod"
 const (
 	ErrMissingInterval = errStr("interval not set")
 	ErrInvalidInterval = errStr("interval must be positive")
+	ErrInvalidSchedule = errStr("invalid schedule")
+	ErrInvalidPolicy   = errStr("invalid error policy")
+	ErrInvalidJitter   = errStr("jitter must not be negative")
+	ErrInvalidOverlap  = errStr("invalid overlap policy")
+	ErrMissingLockKey  = errStr("lock key not set")
+	ErrLockFailed      = errStr("failed to acquire tick lease")
+	ErrLeaseLost       = errStr("tick lease lost")
 	ErrMissingTickFunc = errStr("tick function not set")
+	ErrTickRunning     = errStr("tick already running")
+	ErrTickerStopped   = errStr("ticker stopped")
+	ErrMissingStateKey = errStr("state key not set")
+	ErrInvalidCatchUp  = errStr("invalid catch-up policy")
+	ErrStateFailed     = errStr("failed to load tick state")
+
+	// errLeaseHeld is returned by call when another replica holds the lease.
+	errLeaseHeld = errStr("lease held by another replica")
 )
 
 type errStr string
@@ -21,25 +39,59 @@ type errStr string
 func (e errStr) Error() string { return string(e) }
 
 type Ticker struct {
-	t           *time.Ticker
-	interval    time.Duration
+	sched       schedule
+	clock       Clock
+	loc         *time.Location
+	start       time.Time
 	fn          func(context.Context) error
 	opts        []Opt
 	fireOnStart bool
 	tickTimeout time.Duration
+	policy      ErrorPolicy
+	overlap     OverlapPolicy
+	jitter      time.Duration
+	log         *slog.Logger
+	locker      Locker
+	lockKey     string
+	owner       string
+	name        string
+	tracing     bool
+	metrics     bool
+	tel         *telemetry
+	state       StateStore
+	stateKey    string
+	catchUp     CatchUpPolicy
+
+	mu      sync.Mutex
+	nextRun time.Time
+	pending time.Time
+	lastRun time.Time
+	lastErr error
+
+	missed atomic.Uint64
+	paused atomic.Bool
+	slots  chan struct{}
+	errs   chan error
+	// resched wakes up Run to pick up a pending activation.
+	resched chan struct{}
+	wg      sync.WaitGroup
 
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
 // New creates ticker with given options.
-// WithInterval and WithFunc options are mandatory.
+// WithFunc and either WithInterval or WithSchedule options are mandatory.
 func New(opts ...Opt) *Ticker {
 	return &Ticker{opts: opts}
 }
 
 func (t *Ticker) Init() error {
 	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.clock = realClock{}
+	t.loc = time.Local
+	t.log = slog.Default()
+	t.name = ID
 	for _, opt := range t.opts {
 		if err := opt(t); err != nil {
 			return fmt.Errorf("failed to apply option: %w", err)
@@ -47,56 +99,312 @@ func (t *Ticker) Init() error {
 	}
 
 	switch {
-	case t.t == nil:
+	case t.sched == nil:
 		return ErrMissingInterval
 	case t.fn == nil:
 		return ErrMissingTickFunc
 	}
 
+	tel, err := newTelemetry(t.name, t.tracing, t.metrics)
+	if err != nil {
+		return err
+	}
+	t.tel = tel
+	t.owner = newOwner()
+	t.slots = make(chan struct{}, max(t.overlap.limit, 1))
+	t.errs = make(chan error, 1)
+	t.resched = make(chan struct{}, 1)
+	t.start = t.clock.Now()
 	return nil
 }
 
 func (t *Ticker) Run() error {
-	if t.fireOnStart {
-		if err := t.tick(t.ctx); err != nil {
-			return err
+	last, err := t.restore()
+	if err != nil {
+		return err
+	}
+	if last.IsZero() {
+		last = t.start
+		if t.fireOnStart && !t.paused.Load() {
+			if err := t.fire(t.clock.Now()); err != nil {
+				return err
+			}
+			// Sync next tick to fire one interval after the immediate one,
+			// not interval after Init.
+			last = t.clock.Now()
 		}
-		// Sync next tick to fire one interval after the immediate one,
-		// not interval after Init.
-		t.t.Reset(t.interval)
 	}
+
+	next := t.next(last)
 	for {
+		var wait <-chan time.Time
+		t.setNextRun(next)
+		if !next.IsZero() {
+			wait = t.clock.After(next.Sub(t.clock.Now()) + jitter(t.jitter))
+		}
 		select {
-		case <-t.t.C:
-			if err := t.tick(t.ctx); err != nil {
-				return err
+		case <-wait:
+			if !t.paused.Load() {
+				if err := t.fire(next); err != nil {
+					return err
+				}
+			}
+			// Drain the wake up first so that a delay set after takePending
+			// isn't lost.
+			select {
+			case <-t.resched:
+			default:
+			}
+			if pending, ok := t.takePending(); ok {
+				next = pending
+			} else {
+				next = t.next(next)
 			}
+		case <-t.resched:
+			if pending, ok := t.takePending(); ok {
+				next = pending
+			}
+		case err := <-t.errs:
+			return err
+		case <-t.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// fire runs the tick inline, or in its own goroutine when concurrent
+// ticks are allowed and a slot is free.
+func (t *Ticker) fire(at time.Time) error {
+	if t.overlap.mode == overlapQueue {
+		select {
+		case t.slots <- struct{}{}:
 		case <-t.ctx.Done():
 			return nil
 		}
+	} else {
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			t.addMissed(1)
+			return nil
+		}
+	}
+	if t.overlap.mode != overlapConcurrent {
+		defer func() { <-t.slots }()
+		_, err := t.tick(t.ctx, at)
+		return err
+	}
+	// Stop cancels under the same lock, so no tick is started once it waits.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ctx.Err() != nil {
+		<-t.slots
+		return nil
+	}
+	t.wg.Go(func() {
+		defer func() { <-t.slots }()
+		if _, err := t.tick(t.ctx, at); err != nil {
+			select {
+			case t.errs <- err:
+			default:
+			}
+		}
+	})
+	return nil
+}
+
+// next returns the activation following last. Unless missed activations
+// are queued, the ones already in the past are skipped and counted.
+func (t *Ticker) next(last time.Time) time.Time {
+	sched := t.schedule()
+	next := sched.next(last.In(t.loc))
+	if t.overlap.mode == overlapQueue || next.IsZero() {
+		return next
 	}
+
+	now := t.clock.Now()
+	if !next.Before(now) {
+		return next
+	}
+	if iv, ok := sched.(interval); ok {
+		n := (now.Sub(next) + time.Duration(iv) - 1) / time.Duration(iv)
+		t.addMissed(uint64(n))
+		return next.Add(n * time.Duration(iv))
+	}
+	for !next.IsZero() && next.Before(now) {
+		t.addMissed(1)
+		next = sched.next(next)
+	}
+	return next
 }
 
-func (t *Ticker) tick(ctx context.Context) error {
+// tick calls the tick function for activation at and applies the error
+// policy. It returns the error of the last call and the error left after
+// the policy.
+func (t *Ticker) tick(ctx context.Context, at time.Time) (error, error) {
+	for attempt := 1; ; attempt++ {
+		callErr := t.call(ctx, attempt)
+		switch {
+		case callErr == nil:
+			t.saveState(ctx, at)
+			return nil, nil
+		case errors.Is(callErr, errLeaseHeld):
+			return nil, nil
+		case errors.Is(callErr, context.Canceled) && ctx.Err() != nil:
+			// A tick canceled by Stop or the caller of Trigger isn't a
+			// failure, but it isn't a completed run either.
+			if t.ctx.Err() != nil {
+				return nil, nil
+			}
+			return callErr, nil
+		}
+		delay, retry, err := t.policy.handle(t.log, t.name, attempt, callErr)
+		if !retry {
+			return callErr, err
+		}
+		if !sleep(ctx, t.clock, delay) {
+			return callErr, nil
+		}
+	}
+}
+
+func (t *Ticker) call(ctx context.Context, attempt int) error {
 	if t.tickTimeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, t.tickTimeout)
+		ctx, cancel = withTimeout(ctx, t.clock, t.tickTimeout)
 		defer cancel()
 	}
+	if t.locker != nil {
+		leaseCtx, release, ok, err := t.lease(ctx)
+		switch {
+		case err != nil:
+			return err
+		case !ok:
+			return errLeaseHeld
+		}
+		defer release()
+		ctx = leaseCtx
+	}
+	ctx, span := t.tel.start(ctx, attempt)
+	start := t.clock.Now()
 	err := t.fn(ctx)
-	if errors.Is(err, context.Canceled) {
-		return nil
+	if cause := context.Cause(ctx); errors.Is(err, context.Canceled) && errors.Is(cause, ErrLeaseLost) {
+		err = cause
 	}
+	t.tel.end(ctx, span, start, t.clock.Now(), err)
+	t.setLastRun(start, err)
 	return err
 }
 
+// Stop cancels the context passed to tick functions and waits for
+// concurrent ticks to return. Inline ticks are awaited by Run.
 func (t *Ticker) Stop() error {
+	t.mu.Lock()
 	t.cancel()
-	t.t.Stop()
+	t.mu.Unlock()
+	t.wg.Wait()
 	return nil
 }
 
-func (t *Ticker) ID() string { return ID }
+// ID returns ID followed by the name set by WithName, or just ID when
+// the name isn't set. Options are applied in Init, so ID returns ID until then.
+func (t *Ticker) ID() string {
+	if t.name == "" || t.name == ID {
+		return ID
+	}
+	return ID + "/" + t.name
+}
+
+// Status describes the state of a ticker.
+type Status struct {
+	// Name is the name set by WithName.
+	Name string
+	// NextRun is the next scheduled activation, zero when none is scheduled.
+	NextRun time.Time
+	// LastRun is when the tick function was last called, zero if never.
+	LastRun time.Time
+	// LastError is the error returned by the last tick function call.
+	LastError error
+	// Missed is the number of skipped activations, see Missed.
+	Missed uint64
+	// Paused reports whether scheduled activations are paused.
+	Paused bool
+}
+
+// Status returns the current state of the ticker.
+func (t *Ticker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Status{
+		Name:      t.name,
+		NextRun:   t.nextRun,
+		LastRun:   t.lastRun,
+		LastError: t.lastErr,
+		Missed:    t.missed.Load(),
+		Paused:    t.paused.Load(),
+	}
+}
+
+// SetInterval switches the ticker to a fixed interval of d, replacing the
+// interval or schedule it was created with. The next tick fires d after
+// the call. It can be called any time after Init.
+func (t *Ticker) SetInterval(d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidInterval
+	}
+	t.mu.Lock()
+	t.sched = interval(d)
+	t.mu.Unlock()
+	t.delayNext(d)
+	return nil
+}
+
+func (t *Ticker) schedule() schedule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sched
+}
+
+// delayNext moves the next activation to d from now, keeping the schedule
+// for the ones after it.
+func (t *Ticker) delayNext(d time.Duration) {
+	t.mu.Lock()
+	t.pending = t.clock.Now().Add(d)
+	t.mu.Unlock()
+	select {
+	case t.resched <- struct{}{}:
+	default:
+	}
+}
+
+func (t *Ticker) takePending() (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := t.pending
+	t.pending = time.Time{}
+	return pending, !pending.IsZero()
+}
+
+func (t *Ticker) setNextRun(next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextRun = next
+}
+
+func (t *Ticker) setLastRun(start time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastRun, t.lastErr = start, err
+}
+
+// Missed returns the number of activations skipped because previous ticks
+// were still running.
+func (t *Ticker) Missed() uint64 { return t.missed.Load() }
+
+func (t *Ticker) addMissed(n uint64) {
+	t.missed.Add(n)
+	t.tel.addMissed(t.ctx, n)
+}
 
 type Opt func(*Ticker) error
 
@@ -118,11 +426,35 @@ func WithIntervalFn(fn func() (time.Duration, error)) Opt {
 		if d <= 0 {
 			return ErrInvalidInterval
 		}
-		if t.t != nil {
-			t.t.Stop()
+		t.sched = interval(d)
+		return nil
+	}
+}
+
+// WithSchedule sets a cron schedule instead of a fixed interval.
+// Both "sec min hour dom month dow" and the classic five field form
+// without seconds are accepted, as well as @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight, @hourly and @every <duration> descriptors.
+//
+// Wall clock times are evaluated in the location set by WithLocation.
+// Times skipped by a DST transition fire right after it, shifted forward by
+// the length of the gap. Times repeated when clocks go back fire only once,
+// on their first occurrence. Use UTC to avoid both.
+func WithSchedule(expr string) Opt {
+	return func(t *Ticker) error {
+		s, err := parseCron(expr)
+		if err != nil {
+			return err
 		}
-		t.interval = d
-		t.t = time.NewTicker(d)
+		t.sched = s
+		return nil
+	}
+}
+
+// WithLocation sets the time zone used by WithSchedule. Default is time.Local.
+func WithLocation(loc *time.Location) Opt {
+	return func(t *Ticker) error {
+		t.loc = loc
 		return nil
 	}
 }
@@ -141,6 +473,23 @@ func WithFuncCtx(fn func(context.Context) error) Opt {
 	}
 }
 
+// WithFuncNext sets a tick function that also returns the delay until the
+// next tick, for example to poll more often while there is work to do.
+// A positive delay overrides the schedule for the next activation only,
+// zero or negative keeps the schedule.
+func WithFuncNext(fn func(context.Context) (time.Duration, error)) Opt {
+	return func(t *Ticker) error {
+		t.fn = func(ctx context.Context) error {
+			d, err := fn(ctx)
+			if d > 0 {
+				t.delayNext(d)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
 // WithFireOnStart triggers the tick fn immediately on Run, before the
 // first interval elapses.
 func WithFireOnStart() Opt {
@@ -159,3 +508,130 @@ func WithTickTimeout(d time.Duration) Opt {
 		return nil
 	}
 }
+
+// WithErrorPolicy sets what happens when the tick function returns an error.
+// Default is StopOnError.
+func WithErrorPolicy(p ErrorPolicy) Opt {
+	return func(t *Ticker) error {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		t.policy = p
+		return nil
+	}
+}
+
+// WithJitter delays every tick by a random duration in [0, d) so that
+// replicas sharing a schedule don't fire at the same moment. The jitter
+// doesn't accumulate, each tick is still based on the schedule.
+func WithJitter(d time.Duration) Opt {
+	return func(t *Ticker) error {
+		if d < 0 {
+			return ErrInvalidJitter
+		}
+		t.jitter = d
+		return nil
+	}
+}
+
+// WithLogger sets logger used for reporting tick errors that don't stop
+// the ticker. Default is slog.Default().
+func WithLogger(l *slog.Logger) Opt {
+	return func(t *Ticker) error {
+		t.log = l
+		return nil
+	}
+}
+
+// WithClock replaces the clock driving the ticker, schedule and tick
+// timeouts. Intended for tests, see tickermodtest.Clock.
+func WithClock(c Clock) Opt {
+	return func(t *Ticker) error {
+		t.clock = c
+		return nil
+	}
+}
+
+// WithOverlap sets what happens when an activation is due while previous
+// ticks are still running. Default is OverlapSkip.
+func WithOverlap(p OverlapPolicy) Opt {
+	return func(t *Ticker) error {
+		if p.mode == overlapConcurrent && p.limit < 1 {
+			return ErrInvalidOverlap
+		}
+		t.overlap = p
+		return nil
+	}
+}
+
+// WithLocker makes replicas sharing the same key run each activation only
+// once. Every replica wakes up on schedule, but only the one holding the
+// lease on key runs the tick function and the others skip it. The lease
+// lasts until the next activation and is renewed while the tick runs.
+// Lock errors and ticks canceled because the lease was lost, which fail
+// with ErrLeaseLost, are handled by the error policy like tick errors.
+func WithLocker(l Locker, key string) Opt {
+	return func(t *Ticker) error {
+		if key == "" {
+			return ErrMissingLockKey
+		}
+		t.locker = l
+		t.lockKey = key
+		return nil
+	}
+}
+
+// WithName sets the job name used in logs, spans and metrics. Default is ID.
+func WithName(name string) Opt {
+	return func(t *Ticker) error {
+		t.name = name
+		return nil
+	}
+}
+
+// WithTracing records a span for every tick function call using the global
+// tracer provider, see tracemod.
+func WithTracing() Opt {
+	return func(t *Ticker) error {
+		t.tracing = true
+		return nil
+	}
+}
+
+// WithMetrics records tick duration, succeeded, failed and missed tick
+// counts and the time of the last successful tick using the global meter
+// provider, see metermod. Measurements carry the job.name attribute set
+// by WithName.
+func WithMetrics() Opt {
+	return func(t *Ticker) error {
+		t.metrics = true
+		return nil
+	}
+}
+
+// WithStateStore persists the last successful run under key so that the
+// schedule continues from it after a restart, instead of from Init.
+// Activations missed in between are handled by the catch-up policy, and
+// WithFireOnStart only applies when no state is stored yet.
+func WithStateStore(s StateStore, key string) Opt {
+	return func(t *Ticker) error {
+		if key == "" {
+			return ErrMissingStateKey
+		}
+		t.state = s
+		t.stateKey = key
+		return nil
+	}
+}
+
+// WithCatchUp sets what happens to activations missed since the last run
+// loaded from the state store. Default is CatchUpOnce.
+func WithCatchUp(p CatchUpPolicy) Opt {
+	return func(t *Ticker) error {
+		if p.mode == catchUpAll && p.limit < 1 {
+			return ErrInvalidCatchUp
+		}
+		t.catchUp = p
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+// Package tickermodtest provides helpers for testing code built on tickermod.
+package tickermodtest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+)
+
+var _ tickermod.Clock = (*Clock)(nil)
+
+// Clock is a fake tickermod.Clock whose time only moves with Advance.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewClock creates fake clock starting at given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns channel receiving the fake time once it has advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.add(w)
+	return w.ch
+}
+
+// NewTicker returns ticker firing every d of fake time. Like time.Ticker,
+// ticks are dropped when the receiver falls behind.
+func (c *Clock) NewTicker(d time.Duration) tickermod.ClockTicker {
+	if d <= 0 {
+		panic("tickermodtest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.add(w)
+	return &ticker{c: c, w: w}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker due
+// in order. Timers created by receivers of those ticks are not fired until
+// the next Advance, so step through schedules one activation at a time.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for len(c.waiters) > 0 && !c.waiters[0].at.After(end) {
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		c.now = w.at
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+			c.add(w)
+		}
+	}
+	c.now = end
+}
+
+// BlockUntil blocks until at least n timers or tickers are waiting on the
+// clock. Use it to make sure the code under test has reached its wait
+// before calling Advance. Timers abandoned by their receiver keep counting
+// until they fire.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *Clock) add(w *waiter) {
+	i, _ := slices.BinarySearchFunc(c.waiters, w.at, func(w *waiter, at time.Time) int {
+		if w.at.After(at) {
+			return 1
+		}
+		return -1
+	})
+	c.waiters = slices.Insert(c.waiters, i, w)
+	c.cond.Broadcast()
+}
+
+func (c *Clock) remove(w *waiter) {
+	c.waiters = slices.DeleteFunc(c.waiters, func(o *waiter) bool { return o == w })
+}
+
+type ticker struct {
+	c *Clock
+	w *waiter
+}
+
+func (t *ticker) C() <-chan time.Time { return t.w.ch }
+
+func (t *ticker) Stop() {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	t.c.remove(t.w)
+}
+
+func (t *ticker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("tickermodtest: non-positive interval for Reset")
+	}
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	t.c.remove(t.w)
+	t.w.at = t.c.now.Add(d)
+	t.w.period = d
+	t.c.add(t.w)
+}
@@ -0,0 +1,65 @@
+package tickermodtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestClockAfter(t *testing.T) {
+	c := tickermodtest.NewClock(epoch)
+	late := c.After(2 * time.Second)
+	early := c.After(time.Second)
+
+	c.Advance(500 * time.Millisecond)
+	require.Empty(t, early)
+	require.Equal(t, epoch.Add(500*time.Millisecond), c.Now())
+
+	c.Advance(2 * time.Second)
+	require.Equal(t, epoch.Add(time.Second), <-early)
+	require.Equal(t, epoch.Add(2*time.Second), <-late)
+	require.Equal(t, epoch.Add(2500*time.Millisecond), c.Now())
+}
+
+func TestClockAfterNonPositive(t *testing.T) {
+	c := tickermodtest.NewClock(epoch)
+	require.Equal(t, epoch, <-c.After(0))
+	require.Equal(t, epoch, <-c.After(-time.Second))
+}
+
+func TestClockTicker(t *testing.T) {
+	c := tickermodtest.NewClock(epoch)
+	tk := c.NewTicker(time.Second)
+
+	c.Advance(time.Second)
+	require.Equal(t, epoch.Add(time.Second), <-tk.C())
+
+	// Ticks are dropped when nobody receives them.
+	c.Advance(3 * time.Second)
+	require.Equal(t, epoch.Add(2*time.Second), <-tk.C())
+	require.Empty(t, tk.C())
+
+	tk.Reset(time.Minute)
+	c.Advance(time.Second)
+	require.Empty(t, tk.C())
+	c.Advance(time.Minute)
+	require.Equal(t, epoch.Add(4*time.Second+time.Minute), <-tk.C())
+
+	tk.Stop()
+	c.Advance(time.Hour)
+	require.Empty(t, tk.C())
+}
+
+func TestClockBlockUntil(t *testing.T) {
+	c := tickermodtest.NewClock(epoch)
+	done := make(chan time.Time)
+	go func() { done <- <-c.After(time.Second) }()
+
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	require.Equal(t, epoch.Add(time.Second), <-done)
+}
@@ -0,0 +1,51 @@
+package tickermodtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+)
+
+var _ tickermod.Locker = (*Locker)(nil)
+
+// Locker is an in-memory tickermod.Locker. Share one between tickers to
+// simulate replicas.
+type Locker struct {
+	now    func() time.Time
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+// NewLocker creates in-memory locker reading time from now,
+// for example time.Now or Clock.Now.
+func NewLocker(now func() time.Time) *Locker {
+	return &Locker{now: now, leases: map[string]lease{}}
+}
+
+func (l *Locker) TryLock(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if cur, ok := l.leases[key]; ok && cur.owner != owner && cur.expires.After(now) {
+		return false, nil
+	}
+	l.leases[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Owner returns the current holder of an unexpired lease on key.
+func (l *Locker) Owner(key string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cur, ok := l.leases[key]; ok && cur.expires.After(l.now()) {
+		return cur.owner
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package tickermodtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod/tickermodtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	clock := tickermodtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := tickermodtest.NewLocker(clock.Now)
+
+	ok, err := l.TryLock(ctx, "job", "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "a", l.Owner("job"))
+
+	ok, err = l.TryLock(ctx, "job", "b", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	clock.Advance(time.Minute)
+	require.Empty(t, l.Owner("job"))
+	ok, err = l.TryLock(ctx, "job", "b", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "b", l.Owner("job"))
+}
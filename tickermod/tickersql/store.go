@@ -0,0 +1,188 @@
+// Package tickersql provides tickermod coordination backed by database/sql.
+package tickersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod"
+)
+
+var (
+	_ tickermod.Locker     = (*Store)(nil)
+	_ tickermod.StateStore = (*Store)(nil)
+)
+
+// Store keeps tick leases and last runs in database tables that are
+// created on first use.
+// It works with any database supporting standard SQL, including SQLite,
+// Postgres and MySQL. Leases expire based on the clock of each replica, so
+// keep replica clocks in sync.
+type Store struct {
+	db         func() *sql.DB
+	leaseTable string
+	stateTable string
+	dollar     bool
+	mu         sync.Mutex
+	leaseReady bool
+	stateReady bool
+}
+
+// New creates Store using the database returned by db. The function is
+// called on every use, so a method value like sqlmod.DB.DB can be passed
+// before the database module is initialized.
+func New(db func() *sql.DB, opts ...Opt) *Store {
+	s := &Store{db: db, leaseTable: "tickermod_leases", stateTable: "tickermod_state"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type Opt func(*Store)
+
+// WithDollarPlaceholders uses $1 style placeholders required by Postgres.
+func WithDollarPlaceholders() Opt {
+	return func(s *Store) { s.dollar = true }
+}
+
+// WithLeaseTable sets the table name for leases. Default is tickermod_leases.
+func WithLeaseTable(name string) Opt {
+	return func(s *Store) { s.leaseTable = name }
+}
+
+// WithStateTable sets the table name for last runs. Default is tickermod_state.
+func WithStateTable(name string) Opt {
+	return func(s *Store) { s.stateTable = name }
+}
+
+// TryLock implements tickermod.Locker.
+func (s *Store) TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	if err := s.ensure(ctx, &s.leaseReady, `CREATE TABLE IF NOT EXISTS `+s.leaseTable+` (
+		name VARCHAR(255) NOT NULL PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`); err != nil {
+		return false, err
+	}
+
+	db := s.db()
+	now := time.Now()
+	expires := now.Add(ttl).UnixMilli()
+	res, err := db.ExecContext(ctx, s.bind(`UPDATE `+s.leaseTable+` SET owner = ?, expires_at = ? WHERE name = ? AND (owner = ? OR expires_at <= ?)`),
+		owner, expires, key, owner, now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("failed to update lease: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n > 0 {
+		return true, nil
+	}
+
+	_, err = db.ExecContext(ctx, s.bind(`INSERT INTO `+s.leaseTable+` (name, owner, expires_at) VALUES (?, ?, ?)`), key, owner, expires)
+	if err == nil {
+		return true, nil
+	}
+	// Insert fails on the primary key when someone else holds the lease.
+	var holder string
+	qErr := db.QueryRowContext(ctx, s.bind(`SELECT owner FROM `+s.leaseTable+` WHERE name = ?`), key).Scan(&holder)
+	if qErr == nil && holder != owner {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to insert lease: %w", errors.Join(err, qErr))
+}
+
+// LastRun implements tickermod.StateStore.
+func (s *Store) LastRun(ctx context.Context, key string) (time.Time, error) {
+	if err := s.ensureState(ctx); err != nil {
+		return time.Time{}, err
+	}
+	var ns int64
+	err := s.db().QueryRowContext(ctx, s.bind(`SELECT last_run FROM `+s.stateTable+` WHERE name = ?`), key).Scan(&ns)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, fmt.Errorf("failed to select last run: %w", err)
+	}
+	return time.Unix(0, ns), nil
+}
+
+// SetLastRun implements tickermod.StateStore.
+func (s *Store) SetLastRun(ctx context.Context, key string, t time.Time) error {
+	if err := s.ensureState(ctx); err != nil {
+		return err
+	}
+	db := s.db()
+	res, err := db.ExecContext(ctx, s.bind(`UPDATE `+s.stateTable+` SET last_run = ? WHERE name = ?`), t.UnixNano(), key)
+	if err != nil {
+		return fmt.Errorf("failed to update last run: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, s.bind(`INSERT INTO `+s.stateTable+` (name, last_run) VALUES (?, ?)`), key, t.UnixNano())
+	if err == nil {
+		return nil
+	}
+	// Insert fails on the primary key when another replica inserted the row
+	// first, or when the update didn't change the value and the database
+	// reports no affected rows, like MySQL does.
+	var ns int64
+	if qErr := db.QueryRowContext(ctx, s.bind(`SELECT last_run FROM `+s.stateTable+` WHERE name = ?`), key).Scan(&ns); qErr != nil {
+		return fmt.Errorf("failed to insert last run: %w", errors.Join(err, qErr))
+	}
+	if _, err := db.ExecContext(ctx, s.bind(`UPDATE `+s.stateTable+` SET last_run = ? WHERE name = ?`), t.UnixNano(), key); err != nil {
+		return fmt.Errorf("failed to update last run: %w", err)
+	}
+	return nil
+}
+
+// ensureState creates the state table. Last runs are stored as Unix
+// nanoseconds to keep the phase of interval schedules exact.
+func (s *Store) ensureState(ctx context.Context) error {
+	return s.ensure(ctx, &s.stateReady, `CREATE TABLE IF NOT EXISTS `+s.stateTable+` (
+		name VARCHAR(255) NOT NULL PRIMARY KEY,
+		last_run BIGINT NOT NULL
+	)`)
+}
+
+func (s *Store) ensure(ctx context.Context, ready *bool, ddl string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if *ready {
+		return nil
+	}
+	if _, err := s.db().ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	*ready = true
+	return nil
+}
+
+// bind rewrites ? placeholders to $n when needed.
+func (s *Store) bind(query string) string {
+	if !s.dollar {
+		return query
+	}
+	b := strings.Builder{}
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
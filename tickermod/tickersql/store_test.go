@@ -0,0 +1,108 @@
+package tickersql_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-srvc/mods/tickermod/tickersql"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "tick.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+	return db
+}
+
+func TestTryLock(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	s := tickersql.New(func() *sql.DB { return db })
+
+	ok, err := s.TryLock(ctx, "job", "a", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = s.TryLock(ctx, "job", "b", time.Hour)
+	require.NoError(t, err)
+	require.False(t, ok, "lease held by another owner")
+
+	ok, err = s.TryLock(ctx, "job", "a", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok, "owner renews its own lease")
+
+	ok, err = s.TryLock(ctx, "other", "b", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok, "keys are independent")
+}
+
+func TestTryLockExpired(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	s := tickersql.New(func() *sql.DB { return db }, tickersql.WithLeaseTable("leases"))
+
+	ok, err := s.TryLock(ctx, "job", "a", -time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = s.TryLock(ctx, "job", "b", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok, "expired lease can be taken over")
+
+	var owner string
+	require.NoError(t, db.QueryRow("SELECT owner FROM leases WHERE name = 'job'").Scan(&owner))
+	require.Equal(t, "b", owner)
+}
+
+func TestTryLockError(t *testing.T) {
+	db := openDB(t)
+	require.NoError(t, db.Close())
+	s := tickersql.New(func() *sql.DB { return db })
+
+	_, err := s.TryLock(context.Background(), "job", "a", time.Hour)
+	require.Error(t, err)
+}
+
+func TestDollarPlaceholders(t *testing.T) {
+	// SQLite accepts $n placeholders too.
+	db := openDB(t)
+	s := tickersql.New(func() *sql.DB { return db }, tickersql.WithDollarPlaceholders())
+
+	ok, err := s.TryLock(context.Background(), "job", "a", time.Hour)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLastRun(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	s := tickersql.New(func() *sql.DB { return db }, tickersql.WithStateTable("state"))
+
+	last, err := s.LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, last.IsZero())
+
+	now := time.Now()
+	require.NoError(t, s.SetLastRun(ctx, "job", now))
+	last, err = s.LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, now.Equal(last))
+
+	later := now.Add(time.Hour)
+	require.NoError(t, s.SetLastRun(ctx, "job", later))
+	require.NoError(t, s.SetLastRun(ctx, "job", later))
+	last, err = s.LastRun(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, later.Equal(last))
+
+	last, err = s.LastRun(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, last.IsZero())
+}